@@ -0,0 +1,191 @@
+package webcloud
+
+import (
+	"bytes"
+	sdkjson "encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var defaultSensitiveKeys = []string{"password", "passwd", "secret", "token", "accessToken", "refreshToken", "apiKey", "authorization"}
+
+// AccessLogEntry 访问日志
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Router     string    `json:"router"`
+	Operation  Operation `json:"operation"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	ClientIP   string    `json:"clientIp"`
+	RequestID  string    `json:"requestId,omitempty"`
+	TraceID    string    `json:"traceId,omitempty"`
+	IdentityID any       `json:"identityId,omitempty"`
+	Platform   Platform  `json:"platform,omitempty"`
+	Status     int       `json:"status"`          // 响应状态码 Rest响应时为响应体中的状态码
+	Result     string    `json:"result"`          // 结果分类
+	LatencyMs  float64   `json:"latencyMs"`       // 耗时 毫秒
+	Body       string    `json:"body,omitempty"`  // 脱敏后的请求体摘要
+	Error      string    `json:"error,omitempty"` // 处理错误
+}
+
+// AccessLogSink 访问日志输出
+type AccessLogSink interface {
+	Log(entry *AccessLogEntry)
+}
+
+// logAccessLogSink 输出访问日志至日志
+type logAccessLogSink struct {
+}
+
+func (logAccessLogSink) Log(entry *AccessLogEntry) {
+	logger.Logrus().WithField("access", true).Infoln(json.ToString(entry))
+}
+
+// AccessLogConfig 访问日志配置
+type AccessLogConfig struct {
+	Sink          AccessLogSink // 访问日志输出 默认输出至日志
+	SensitiveKeys []string      // 请求体中需脱敏的json key 不区分大小写 为空时使用默认设置
+	MaxBodyLength int           // 请求体摘要的最大长度 默认512 小于0时不记录请求体
+	MaxBodyBytes  int64         // 记录请求体摘要的最大请求体大小 默认64KB 超出时仅记录大小
+}
+
+type accessLogConfig struct {
+	AccessLogConfig
+	sensitiveKeys map[string]struct{}
+}
+
+// SetAccessLog 启用基础操作的访问日志
+func (b *BaseRouter[ID, S, M, Q, D]) SetAccessLog(config AccessLogConfig) *BaseRouter[ID, S, M, Q, D] {
+	if config.Sink == nil {
+		config.Sink = logAccessLogSink{}
+	}
+	if len(config.SensitiveKeys) == 0 {
+		config.SensitiveKeys = defaultSensitiveKeys
+	}
+	if config.MaxBodyLength == 0 {
+		config.MaxBodyLength = 512
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 64 << 10
+	}
+	result := &accessLogConfig{AccessLogConfig: config, sensitiveKeys: make(map[string]struct{})}
+	for _, key := range config.SensitiveKeys {
+		result.sensitiveKeys[normalizeKey(key)] = struct{}{}
+	}
+	b.accessLog = result
+	return b
+}
+
+// normalizeKey 统一json key的大小写与分隔符 使 accessToken access_token 视为相同
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// bodySummary 获取脱敏后的请求体摘要
+func (c *accessLogConfig) bodySummary(request *ginstarter.Request) string {
+	if c.MaxBodyLength < 0 {
+		return ""
+	}
+	rawRequest := request.RawGinContext().Request
+	if rawRequest.ContentLength == 0 || rawRequest.Body == nil {
+		return ""
+	}
+	if rawRequest.ContentLength < 0 || rawRequest.ContentLength > c.MaxBodyBytes {
+		return "<" + strconv.FormatInt(rawRequest.ContentLength, 10) + " bytes>"
+	}
+	if !strings.HasPrefix(request.RawGinContext().ContentType(), gin.MIMEJSON) {
+		return "<" + strconv.FormatInt(rawRequest.ContentLength, 10) + " bytes>"
+	}
+	body, err := readRawBody(request, c.MaxBodyBytes)
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+	decoder := sdkjson.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err = decoder.Decode(&value); err != nil {
+		return "<invalid json " + strconv.Itoa(len(body)) + " bytes>"
+	}
+	summary := json.ToString(c.maskValue(value))
+	if runes := []rune(summary); len(runes) > c.MaxBodyLength {
+		summary = string(runes[:c.MaxBodyLength]) + "..."
+	}
+	return summary
+}
+
+// maskValue 递归脱敏敏感字段
+func (c *accessLogConfig) maskValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, ok := c.sensitiveKeys[normalizeKey(key)]; ok {
+				v[key] = "***"
+				continue
+			}
+			v[key] = c.maskValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = c.maskValue(item)
+		}
+	}
+	return value
+}
+
+// logAccess 记录基础操作的访问日志
+func (b *BaseRouter[ID, S, M, Q, D]) logAccess(request *ginstarter.Request, operation Operation, handler ginstarter.HandlerWrapper) (response ginstarter.Response, err error) {
+	config := b.accessLog
+	if config == nil {
+		return handler(request)
+	}
+	start := time.Now()
+	entry := &AccessLogEntry{
+		Time:      start,
+		Router:    b.resource,
+		Operation: operation,
+		Method:    request.HttpMethod(),
+		Path:      request.RequestPath(),
+		ClientIP:  request.RequestIP(),
+		RequestID: request.GetHeader(HeaderRequestID),
+		Body:      config.bodySummary(request),
+	}
+	if spanContext := trace.SpanContextFromContext(request.RawGinContext().Request.Context()); spanContext.HasTraceID() {
+		entry.TraceID = spanContext.TraceID().String()
+	}
+	completed := false
+	defer func() {
+		var recovered any
+		if !completed {
+			recovered = recover()
+		}
+		entry.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		// 仅读取已获取的认证信息 认证失败中断请求时不再重复执行AuthorityFetch
+		if authority := cachedAuthority[ID](request); authority != nil {
+			entry.IdentityID = authority.GetIdentityID()
+			entry.Platform = authority.GetPlatform()
+		}
+		if completed {
+			entry.Result, entry.Status = responseResult(response, err)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+		} else {
+			entry.Result, entry.Status = panicResult(requestAbort(request), recovered)
+			entry.Error = errPanic.Error()
+		}
+		config.Sink.Log(entry)
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+	response, err = handler(request)
+	completed = true
+	return
+}
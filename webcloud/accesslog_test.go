@@ -0,0 +1,151 @@
+package webcloud
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+type memoryAccessLogSink struct {
+	mutex   sync.Mutex
+	entries []*AccessLogEntry
+}
+
+func (m *memoryAccessLogSink) Log(entry *AccessLogEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries = append(m.entries, entry)
+}
+
+func (m *memoryAccessLogSink) last(t *testing.T) *AccessLogEntry {
+	t.Helper()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.entries) == 0 {
+		t.Fatal("no access log entry")
+	}
+	return m.entries[len(m.entries)-1]
+}
+
+func accessLogRouter(config AccessLogConfig) (*testRouter, *memoryAccessLogSink) {
+	sink := &memoryAccessLogSink{}
+	config.Sink = sink
+	router := (&testRouter{resource: "test"}).SetAccessLog(config)
+	return router, sink
+}
+
+func TestAccessLogMasksBody(t *testing.T) {
+	router, sink := accessLogRouter(AccessLogConfig{})
+	handler := router.wrap(OperationSave, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{body: `{"name":"a","password":"p","profile":{"access_token":"t1","accessToken":"t2",` +
+		`"Refresh-Token":"t3","keys":[{"apiKey":"k","id":1}]}}`})
+	entry := sink.last(t)
+	for _, secret := range []string{`"p"`, "t1", "t2", "t3", `"k"`} {
+		if strings.Contains(entry.Body, secret) {
+			t.Fatalf("secret %s not masked: %s", secret, entry.Body)
+		}
+	}
+	for _, kept := range []string{`"name":"a"`, `"id":1`} {
+		if !strings.Contains(entry.Body, kept) {
+			t.Fatalf("non sensitive value %s lost: %s", kept, entry.Body)
+		}
+	}
+	if entry.Status != http.StatusOK || entry.Result != ResultSuccess || entry.Operation != OperationSave {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestAccessLogCustomKeysAndTruncation(t *testing.T) {
+	router, sink := accessLogRouter(AccessLogConfig{SensitiveKeys: []string{"phone_number"}, MaxBodyLength: 16})
+	handler := router.wrap(OperationSave, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{body: `{"phoneNumber":"13800000000","remark":"这是一段很长的备注内容"}`})
+	entry := sink.last(t)
+	if strings.Contains(entry.Body, "13800000000") {
+		t.Fatalf("custom sensitive key not masked: %s", entry.Body)
+	}
+	if !strings.HasSuffix(entry.Body, "...") || len([]rune(entry.Body)) != 16+3 {
+		t.Fatalf("body summary not truncated to 16 runes: %s", entry.Body)
+	}
+}
+
+func TestAccessLogSkipsBody(t *testing.T) {
+	router, sink := accessLogRouter(AccessLogConfig{MaxBodyLength: -1})
+	handler := router.wrap(OperationSave, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{body: `{"name":"a"}`})
+	if body := sink.last(t).Body; body != "" {
+		t.Fatalf("body recorded although disabled: %s", body)
+	}
+
+	router, sink = accessLogRouter(AccessLogConfig{MaxBodyBytes: 8})
+	handler = router.wrap(OperationSave, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{body: `{"name":"abcdefgh"}`})
+	if body := sink.last(t).Body; body != "<19 bytes>" {
+		t.Fatalf("oversized body should be recorded by size: %s", body)
+	}
+}
+
+func TestAccessLogPanicStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		panic  func(request *ginstarter.Request)
+		result string
+		status int
+	}{
+		{"aborted", func(request *ginstarter.Request) {
+			AbortRequest(request, http.StatusForbidden, errors.New("forbidden"))
+		}, ResultClientError, http.StatusForbidden},
+		{"request panic", func(request *ginstarter.Request) {
+			request.Panic(http.StatusBadRequest, errors.New("bad"))
+		}, ResultClientError, http.StatusBadRequest},
+		{"runtime", func(request *ginstarter.Request) { panic("boom") }, ResultServerError, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		router, sink := accessLogRouter(AccessLogConfig{})
+		handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+			c.panic(request)
+			return ginstarter.RespRestSuccess(), nil
+		})
+		result := serve(t, handler, testCall{method: http.MethodGet})
+		entry := sink.last(t)
+		if entry.Result != c.result || entry.Status != c.status || entry.Error == "" {
+			t.Errorf("%s: entry %s %d %q, want %s %d", c.name, entry.Result, entry.Status, entry.Error, c.result, c.status)
+		}
+		if int(result.status) != c.status {
+			t.Errorf("%s: response status %d %d, want %d", c.name, result.httpStatus, result.status, c.status)
+		}
+	}
+}
+
+func TestAccessLogDoesNotRefetchFailedAuthority(t *testing.T) {
+	router, sink := accessLogRouter(AccessLogConfig{})
+	calls := 0
+	router.authorityFetch = func(request *ginstarter.Request) Authority[int64] {
+		calls++
+		AbortRequest(request, http.StatusUnauthorized, errors.New("token expired"))
+		return nil
+	}
+	handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		router.GetAuthorityData(request)
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{method: http.MethodGet})
+	entry := sink.last(t)
+	if calls != 1 {
+		t.Fatalf("authority fetch executed %d times", calls)
+	}
+	if entry.Status != http.StatusUnauthorized || entry.IdentityID != nil {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
@@ -0,0 +1,254 @@
+package webcloud
+
+import (
+	"maps"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/acexy/golang-toolkit/util/str"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	AuditActionBypass = "bypass" // 跳过数据权限控制
+	AuditActionSave   = "save"   // 保存数据
+	AuditActionModify = "modify" // 修改数据
+	AuditActionRemove = "remove" // 删除数据
+)
+
+const (
+	AuditResultSuccess  = "success"   // 执行成功
+	AuditResultNotFound = "not-found" // 未影响任何数据
+	AuditResultFailure  = "failure"   // 执行失败
+)
+
+// AuditEvent 审计事件
+type AuditEvent struct {
+	Time          time.Time      `json:"time"`
+	Action        string         `json:"action"`    // 审计动作
+	Resource      string         `json:"resource"`  // 资源名称
+	Operation     Operation      `json:"operation"` // 基础操作
+	ResourceID    any            `json:"resourceId,omitempty"`
+	IdentityID    any            `json:"identityId"`               // 有效身份
+	RealID        any            `json:"realIdentityId,omitempty"` // 代理其他身份时的实际身份
+	Platform      Platform       `json:"platform"`
+	ClientIP      string         `json:"clientIp"`
+	Path          string         `json:"path"`
+	ChangedFields []string       `json:"changedFields,omitempty"` // 变更的数据库字段
+	Before        map[string]any `json:"before,omitempty"`        // 变更前的数据
+	After         map[string]any `json:"after,omitempty"`         // 变更后的数据
+	Result        string         `json:"result,omitempty"`        // 执行结果
+	Error         string         `json:"error,omitempty"`
+}
+
+// AuditSink 审计事件输出
+type AuditSink interface {
+	Record(event *AuditEvent)
+}
+
+// logAuditSink 输出审计事件至日志
+type logAuditSink struct {
+}
+
+func (logAuditSink) Record(event *AuditEvent) {
+	logger.Logrus().WithField("audit", true).Infoln(json.ToString(event))
+}
+
+// FileAuditSink 以JSONL格式输出审计事件至文件 每行一个事件
+type FileAuditSink struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewFileAuditSink 创建JSONL文件审计事件输出 文件不存在时自动创建 存在时追加写入
+func NewFileAuditSink(filePath string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+func (f *FileAuditSink) Record(event *AuditEvent) {
+	line, err := json.ToBytesError(event)
+	if err != nil {
+		logger.Logrus().Errorln("encode audit event error:", err)
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		logger.Logrus().Errorln("write audit event error:", err)
+	}
+}
+
+// Close 关闭文件
+func (f *FileAuditSink) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// MemoryAuditSink 基于内存的审计事件输出 仅保留最近的事件 适用于测试
+type MemoryAuditSink struct {
+	mutex    sync.RWMutex
+	capacity int
+	events   []*AuditEvent
+}
+
+// NewMemoryAuditSink 创建基于内存的审计事件输出 capacity 为最多保留的事件数量 小于等于0时不限制
+func NewMemoryAuditSink(capacity int) *MemoryAuditSink {
+	return &MemoryAuditSink{capacity: capacity}
+}
+
+func (m *MemoryAuditSink) Record(event *AuditEvent) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.events = append(m.events, event)
+	if m.capacity > 0 && len(m.events) > m.capacity {
+		m.events = m.events[len(m.events)-m.capacity:]
+	}
+}
+
+// Events 获取已记录的事件
+func (m *MemoryAuditSink) Events() []*AuditEvent {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return append([]*AuditEvent(nil), m.events...)
+}
+
+// Reset 清空已记录的事件
+func (m *MemoryAuditSink) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.events = nil
+}
+
+// SetAuditSink 设置审计事件输出 设置后将记录保存、修改、删除操作的审计事件
+// 未设置时仅跳过数据权限控制、代理身份等事件输出至日志
+func (b *BaseRouter[ID, S, M, Q, D]) SetAuditSink(sink AuditSink) *BaseRouter[ID, S, M, Q, D] {
+	b.auditSink = sink
+	return b
+}
+
+// SetAuditRedactFields 设置审计事件数据快照中删除的结构体字段名 例如密码等不应记录的字段
+// 数据快照同时按响应结构体D的脱敏标签脱敏 不受查看原文权限的影响
+func (b *BaseRouter[ID, S, M, Q, D]) SetAuditRedactFields(fieldNames ...string) *BaseRouter[ID, S, M, Q, D] {
+	b.auditRedactColumns = structNames2Columns(fieldNames)
+	return b
+}
+
+// redactAuditImage 对审计事件的数据快照脱敏 快照的key可为json字段名或数据库字段名 不修改原数据
+func (b *BaseRouter[ID, S, M, Q, D]) redactAuditImage(image map[string]any) map[string]any {
+	fields := parseMaskFields(reflect.TypeFor[D]())
+	if image == nil || len(fields) == 0 && len(b.auditRedactColumns) == 0 {
+		return image
+	}
+	result := maps.Clone(image)
+	for key, value := range image {
+		column := str.CamelToSnake(key)
+		if coll.SliceContains(b.auditRedactColumns, column) {
+			delete(result, key)
+			continue
+		}
+		for _, field := range fields {
+			if field.jsonName != key && str.CamelToSnake(field.jsonName) != column {
+				continue
+			}
+			if field.kind == MaskDrop {
+				delete(result, key)
+			} else {
+				result[key] = maskValue(value, field.kind)
+			}
+		}
+	}
+	return result
+}
+
+// newAuditEvent 创建当前请求的审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) newAuditEvent(request *ginstarter.Request, action string, authority Authority[ID]) *AuditEvent {
+	return newAuditEvent(request, b.resource, action, authority)
+}
+
+func newAuditEvent[ID IDType](request *ginstarter.Request, resource, action string, authority Authority[ID]) *AuditEvent {
+	event := &AuditEvent{
+		Time:     time.Now(),
+		Action:   action,
+		Resource: resource,
+		ClientIP: request.RequestIP(),
+		Path:     request.RequestPath(),
+	}
+	event.Operation, _ = GetOperation(request)
+	if authority != nil {
+		event.IdentityID = authority.GetIdentityID()
+		event.Platform = authority.GetPlatform()
+		if impersonated, ok := authority.(*ImpersonatedAuthority[ID]); ok {
+			event.RealID = impersonated.Real.GetIdentityID()
+		}
+	}
+	return event
+}
+
+// audit 输出审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) audit(event *AuditEvent) {
+	if b.auditSink != nil {
+		b.auditSink.Record(event)
+		return
+	}
+	logAuditSink{}.Record(event)
+}
+
+// auditEnabled 是否记录数据变更的审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) auditEnabled() bool {
+	return b.auditSink != nil
+}
+
+// auditChange 记录数据变更的审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) auditChange(request *ginstarter.Request, action string, id any, changed []string, before, after map[string]any, row int64, err error) {
+	if !b.auditEnabled() {
+		return
+	}
+	var authority Authority[ID]
+	if b.authorityFetch != nil {
+		authority = b.GetAuthorityData(request, true)
+	}
+	event := b.newAuditEvent(request, action, authority)
+	event.ResourceID = id
+	event.ChangedFields = changed
+	event.Before = b.redactAuditImage(before)
+	event.After = b.redactAuditImage(after)
+	switch {
+	case err != nil:
+		event.Result = AuditResultFailure
+		event.Error = err.Error()
+	case row == 0:
+		event.Result = AuditResultNotFound
+	default:
+		event.Result = AuditResultSuccess
+	}
+	b.audit(event)
+}
+
+// snapshot 获取满足条件的数据快照 key为json字段名 数据不存在时返回nil
+func (b *BaseRouter[ID, S, M, Q, D]) snapshot(request *ginstarter.Request, condition map[string]any) map[string]any {
+	var d D
+	row, err := b.bizService.BaseQueryByIDContext(b.requestContext(request), maps.Clone(condition), &d)
+	if err != nil {
+		logger.Logrus().Warningln("query snapshot error:", err)
+		return nil
+	}
+	if row == 0 {
+		return nil
+	}
+	result, err := toJsonMap(&d)
+	if err != nil {
+		logger.Logrus().Warningln("convert snapshot error:", err)
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package webcloud
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testAuditRecord struct {
+	ID       int64  `json:"id"`
+	Phone    string `json:"phone" mask:"phone,perm=user:pii"`
+	Secret   string `json:"secret" mask:"drop"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+type testAuditRouter = BaseRouter[int64, testAuditRecord, testAuditRecord, testAuditRecord, testAuditRecord]
+
+func TestRedactAuditImage(t *testing.T) {
+	router := (&testAuditRouter{}).SetAuditRedactFields("Password")
+	cases := []struct {
+		name  string
+		image map[string]any
+		want  map[string]any
+	}{
+		{
+			"json keys",
+			map[string]any{"id": 1, "phone": "13812345678", "secret": "s", "password": "p", "name": "n"},
+			map[string]any{"id": 1, "phone": "138****5678", "name": "n"},
+		},
+		{
+			"column keys",
+			map[string]any{"phone": "13812345678", "secret": "s", "password": "p"},
+			map[string]any{"phone": "138****5678"},
+		},
+		{"nil", nil, nil},
+	}
+	for _, c := range cases {
+		var original map[string]any
+		if c.image != nil {
+			original = map[string]any{}
+			for k, v := range c.image {
+				original[k] = v
+			}
+		}
+		if got := router.redactAuditImage(c.image); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: redactAuditImage = %v, want %v", c.name, got, c.want)
+		}
+		if !reflect.DeepEqual(c.image, original) {
+			t.Errorf("%s: source image modified: %v", c.name, c.image)
+		}
+	}
+}
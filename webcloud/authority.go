@@ -0,0 +1,142 @@
+package webcloud
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const ctxKeyAuthority = "_webcloud_authority"
+
+// ClientAuthority 服务间调用方的认证信息 由APIKey/HMAC等凭证解析得到
+type ClientAuthority[ID IDType] struct {
+	IdentityID ID
+	Platform   Platform
+	Scopes     []string // 凭证被授予的权限范围
+	AccessKey  string   // 凭证标识 APIKey为摘要 HMAC为AccessKey
+}
+
+func (c *ClientAuthority[ID]) GetIdentityID() ID {
+	return c.IdentityID
+}
+
+func (c *ClientAuthority[ID]) GetPlatform() Platform {
+	return c.Platform
+}
+
+// GetScopes 获取凭证被授予的权限范围
+func (c *ClientAuthority[ID]) GetScopes() []string {
+	return c.Scopes
+}
+
+// HasScope 判断凭证是否被授予了指定的权限范围
+func (c *ClientAuthority[ID]) HasScope(scope string) bool {
+	return coll.SliceContains(c.Scopes, scope)
+}
+
+// fetchAuthority 获取当前请求的认证信息 同一请求内仅执行一次AuthorityFetch 中断请求的失败结果同样缓存
+// 带有防重放校验的认证方式(如HMAC)与记录审计事件的身份代理重复执行将导致校验失败或重复记录
+func fetchAuthority[ID IDType](request *ginstarter.Request, authorityFetch AuthorityFetch[ID]) Authority[ID] {
+	if v, ok := request.GetValue(ctxKeyAuthority); ok {
+		authority, _ := v.(Authority[ID])
+		return authority
+	}
+	completed := false
+	defer func() {
+		if !completed {
+			// 认证失败中断请求时同样缓存 后续流程不再重复执行AuthorityFetch
+			request.SetValue(ctxKeyAuthority, nil)
+		}
+	}()
+	authority := authorityFetch(request)
+	completed = true
+	request.SetValue(ctxKeyAuthority, authority)
+	return authority
+}
+
+// cachedAuthority 获取当前请求已获取的认证信息 不执行AuthorityFetch 用于请求结束后记录访问日志等
+func cachedAuthority[ID IDType](request *ginstarter.Request) Authority[ID] {
+	v, _ := request.GetValue(ctxKeyAuthority)
+	authority, _ := v.(Authority[ID])
+	return authority
+}
+
+const ctxKeyAbort = "_webcloud_abort"
+
+// RequestAbort 中断请求流程的状态 由 AbortRequest 记录
+type RequestAbort struct {
+	StatusCode int
+	Err        error
+}
+
+// AbortRequest 以指定状态码中断请求流程 与 request.Panic 一致由ginstarter响应
+// 同时记录中断状态 使基础路由的访问日志、指标与追踪按该状态码分类 认证方式等需中断请求时应使用该方法
+func AbortRequest(request *ginstarter.Request, statusCode int, err error) {
+	request.SetValue(ctxKeyAbort, &RequestAbort{StatusCode: statusCode, Err: err})
+	request.Panic(statusCode, err)
+}
+
+// requestAbort 获取 AbortRequest 记录的中断状态
+func requestAbort(request *ginstarter.Request) *RequestAbort {
+	v, _ := request.GetValue(ctxKeyAbort)
+	abort, _ := v.(*RequestAbort)
+	return abort
+}
+
+// readRawBody 读取请求body 超出limit字节时返回 BodyError 读取后回填body以便后续流程再次读取
+func readRawBody(request *ginstarter.Request, limit int64) ([]byte, error) {
+	tooLarge := &BodyError{Offset: limit, Reason: fmt.Sprintf("request body exceeds %d bytes", limit)}
+	if body := cachedBody(request); body != nil {
+		if int64(len(body)) > limit {
+			return nil, tooLarge
+		}
+		return body, nil
+	}
+	ctx := request.RawGinContext()
+	if ctx.Request.ContentLength > limit {
+		return nil, tooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, tooLarge
+	}
+	setRawBody(request, body)
+	return body, nil
+}
+
+// cachedBody 获取已读取并缓存的请求体 未读取时返回nil
+func cachedBody(request *ginstarter.Request) []byte {
+	if v, ok := request.RawGinContext().Get(gin.BodyBytesKey); ok {
+		body, _ := v.([]byte)
+		return body
+	}
+	return nil
+}
+
+// authorityUnwrapper 包装了其他认证信息的认证信息
+type authorityUnwrapper[ID IDType] interface {
+	Unwrap() Authority[ID]
+}
+
+// AuthorityAs 在认证信息及其包装链中查找指定类型的认证信息
+// 用于在组合认证等包装场景下获取原始认证信息实现的可选接口
+func AuthorityAs[T any, ID IDType](authority Authority[ID]) (T, bool) {
+	for authority != nil {
+		if target, ok := authority.(T); ok {
+			return target, true
+		}
+		unwrapper, ok := authority.(authorityUnwrapper[ID])
+		if !ok {
+			break
+		}
+		authority = unwrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,110 @@
+package webcloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/crypto/hashing"
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const defaultAPIKeyHeader = "X-Api-Key"
+
+// APIKeyCredential APIKey凭证 存储中仅保存APIKey的摘要 不保存明文
+type APIKeyCredential[ID IDType] struct {
+	KeyHash    string // APIKey摘要 见 HashAPIKey
+	IdentityID ID
+	Platform   Platform
+	Scopes     []string  // 该APIKey被授予的权限范围
+	Disabled   bool      // 是否已禁用
+	ExpireAt   time.Time // 过期时间 零值表示永不过期
+}
+
+// APIKeyStore APIKey凭证存储
+type APIKeyStore[ID IDType] interface {
+	// FindByHash 通过APIKey摘要查询凭证 凭证不存在时返回nil
+	FindByHash(keyHash string) (*APIKeyCredential[ID], error)
+}
+
+// HashAPIKey 计算APIKey摘要 存储凭证时应使用该摘要
+func HashAPIKey(apiKey string) string {
+	return hashing.Sha256Hex(apiKey)
+}
+
+// MemoryAPIKeyStore 基于内存的APIKey凭证存储
+type MemoryAPIKeyStore[ID IDType] struct {
+	mutex       sync.RWMutex
+	credentials map[string]*APIKeyCredential[ID]
+}
+
+// NewMemoryAPIKeyStore 创建基于内存的APIKey凭证存储
+func NewMemoryAPIKeyStore[ID IDType]() *MemoryAPIKeyStore[ID] {
+	return &MemoryAPIKeyStore[ID]{
+		credentials: make(map[string]*APIKeyCredential[ID]),
+	}
+}
+
+// Add 添加凭证
+func (m *MemoryAPIKeyStore[ID]) Add(credential *APIKeyCredential[ID]) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.credentials[credential.KeyHash] = credential
+}
+
+// Remove 移除凭证
+func (m *MemoryAPIKeyStore[ID]) Remove(keyHash string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.credentials, keyHash)
+}
+
+func (m *MemoryAPIKeyStore[ID]) FindByHash(keyHash string) (*APIKeyCredential[ID], error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.credentials[keyHash], nil
+}
+
+// APIKeyFetchConfig APIKey认证配置
+type APIKeyFetchConfig struct {
+	HeaderName string // 携带APIKey的请求头 默认 X-Api-Key
+}
+
+// NewAPIKeyAuthorityFetch 创建基于APIKey的认证方式
+// 请求方通过请求头携带APIKey明文 服务端以其摘要查询凭证 认证成功后返回 *ClientAuthority
+func NewAPIKeyAuthorityFetch[ID IDType](store APIKeyStore[ID], config ...APIKeyFetchConfig) AuthorityFetch[ID] {
+	headerName := defaultAPIKeyHeader
+	if len(config) > 0 && config[0].HeaderName != "" {
+		headerName = config[0].HeaderName
+	}
+	return func(request *ginstarter.Request) Authority[ID] {
+		apiKey := request.GetHeader(headerName)
+		if apiKey == "" {
+			return nil
+		}
+		keyHash := HashAPIKey(apiKey)
+		credential, err := store.FindByHash(keyHash)
+		if err != nil {
+			logger.Logrus().Errorln("find api key credential error:", err)
+			return nil
+		}
+		if credential == nil {
+			logger.Logrus().Warningln("unknown api key, request ip:", request.RequestIP())
+			return nil
+		}
+		if credential.Disabled {
+			logger.Logrus().Warningln("api key disabled:", keyHash)
+			return nil
+		}
+		if !credential.ExpireAt.IsZero() && time.Now().After(credential.ExpireAt) {
+			logger.Logrus().Warningln("api key expired:", keyHash)
+			return nil
+		}
+		return &ClientAuthority[ID]{
+			IdentityID: credential.IdentityID,
+			Platform:   credential.Platform,
+			Scopes:     credential.Scopes,
+			AccessKey:  keyHash,
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package webcloud
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// fetchHandler 执行认证 成功时响应认证信息的身份标识
+func fetchHandler(fetch AuthorityFetch[int64]) ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+		authority := fetch(request)
+		if authority == nil {
+			return ginstarter.RespRestUnAuthorized(), nil
+		}
+		return ginstarter.RespRestSuccess(authority.GetIdentityID()), nil
+	}
+}
+
+// fetchIdentity 以指定请求执行认证 认证失败时返回0
+func fetchIdentity(t *testing.T, fetch AuthorityFetch[int64], call testCall) int64 {
+	t.Helper()
+	result := serve(t, fetchHandler(fetch), call)
+	if result.status != ginstarter.StatusCodeSuccess {
+		return 0
+	}
+	var id int64
+	result.decodeData(t, &id)
+	return id
+}
+
+func TestAPIKeyAuthorityFetch(t *testing.T) {
+	store := NewMemoryAPIKeyStore[int64]()
+	store.Add(&APIKeyCredential[int64]{KeyHash: HashAPIKey("valid"), IdentityID: 1, Scopes: []string{"read"}})
+	store.Add(&APIKeyCredential[int64]{KeyHash: HashAPIKey("disabled"), IdentityID: 2, Disabled: true})
+	store.Add(&APIKeyCredential[int64]{KeyHash: HashAPIKey("expired"), IdentityID: 3, ExpireAt: time.Now().Add(-time.Minute)})
+	store.Add(&APIKeyCredential[int64]{KeyHash: HashAPIKey("expiring"), IdentityID: 4, ExpireAt: time.Now().Add(time.Hour)})
+	// 存储中以明文作为摘要的凭证不可通过明文认证
+	store.Add(&APIKeyCredential[int64]{KeyHash: "plain", IdentityID: 5})
+	fetch := NewAPIKeyAuthorityFetch[int64](store)
+	cases := []struct {
+		name   string
+		header map[string]string
+		id     int64
+	}{
+		{"valid", map[string]string{defaultAPIKeyHeader: "valid"}, 1},
+		{"expiring", map[string]string{defaultAPIKeyHeader: "expiring"}, 4},
+		{"disabled", map[string]string{defaultAPIKeyHeader: "disabled"}, 0},
+		{"expired", map[string]string{defaultAPIKeyHeader: "expired"}, 0},
+		{"unknown", map[string]string{defaultAPIKeyHeader: "unknown"}, 0},
+		{"plain text lookup", map[string]string{defaultAPIKeyHeader: "plain"}, 0},
+		{"missing", nil, 0},
+	}
+	for _, c := range cases {
+		if id := fetchIdentity(t, fetch, testCall{method: http.MethodGet, header: c.header}); id != c.id {
+			t.Errorf("%s: identity %d, want %d", c.name, id, c.id)
+		}
+	}
+
+	custom := NewAPIKeyAuthorityFetch[int64](store, APIKeyFetchConfig{HeaderName: "X-Key"})
+	if id := fetchIdentity(t, custom, testCall{method: http.MethodGet, header: map[string]string{"X-Key": "valid"}}); id != 1 {
+		t.Errorf("custom header: identity %d, want 1", id)
+	}
+}
+
+func TestAPIKeyClientAuthority(t *testing.T) {
+	store := NewMemoryAPIKeyStore[int64]()
+	store.Add(&APIKeyCredential[int64]{KeyHash: HashAPIKey("valid"), IdentityID: 1, Platform: "open", Scopes: []string{"read"}})
+	fetch := NewAPIKeyAuthorityFetch[int64](store)
+	handler := func(request *ginstarter.Request) (ginstarter.Response, error) {
+		client, ok := fetch(request).(*ClientAuthority[int64])
+		if !ok || client.AccessKey != HashAPIKey("valid") || client.Platform != "open" || !client.HasScope("read") {
+			t.Errorf("unexpected client authority: %+v", client)
+		}
+		return ginstarter.RespRestSuccess(), nil
+	}
+	serve(t, handler, testCall{method: http.MethodGet, header: map[string]string{defaultAPIKeyHeader: "valid"}})
+}
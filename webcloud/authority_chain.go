@@ -0,0 +1,109 @@
+package webcloud
+
+import (
+	"strings"
+
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// AuthScheme 认证方式
+type AuthScheme string
+
+const (
+	AuthSchemeBearer AuthScheme = "bearer"  // Authorization: Bearer <token>
+	AuthSchemeCookie AuthScheme = "cookie"  // 会话Cookie
+	AuthSchemeAPIKey AuthScheme = "api-key" // APIKey
+	AuthSchemeHMAC   AuthScheme = "hmac"    // HMAC请求签名
+)
+
+// SchemeAuthority 记录了认证方式的认证信息
+type SchemeAuthority[ID IDType] struct {
+	Authority[ID]
+	Scheme AuthScheme
+}
+
+// GetScheme 获取认证成功的认证方式
+func (s *SchemeAuthority[ID]) GetScheme() AuthScheme {
+	return s.Scheme
+}
+
+// Unwrap 获取原始认证信息
+func (s *SchemeAuthority[ID]) Unwrap() Authority[ID] {
+	return s.Authority
+}
+
+// AuthSchemeFetch 组合认证中的单个认证方式
+type AuthSchemeFetch[ID IDType] struct {
+	Scheme AuthScheme
+	// Match 判断请求是否携带了该认证方式的凭证 为nil时总是尝试该认证方式
+	Match func(request *ginstarter.Request) bool
+	Fetch AuthorityFetch[ID]
+}
+
+// NewChainAuthorityFetch 创建组合认证方式
+// 按顺序尝试各认证方式 跳过请求未携带凭证的方式 首个认证成功的结果将以 *SchemeAuthority 返回
+func NewChainAuthorityFetch[ID IDType](schemes ...AuthSchemeFetch[ID]) AuthorityFetch[ID] {
+	return func(request *ginstarter.Request) Authority[ID] {
+		for _, scheme := range schemes {
+			if scheme.Match != nil && !scheme.Match(request) {
+				continue
+			}
+			authority := scheme.Fetch(request)
+			if authority != nil {
+				return &SchemeAuthority[ID]{
+					Authority: authority,
+					Scheme:    scheme.Scheme,
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// HeaderPresent 请求携带了指定请求头
+func HeaderPresent(name string) func(request *ginstarter.Request) bool {
+	return func(request *ginstarter.Request) bool {
+		return request.GetHeader(name) != ""
+	}
+}
+
+// CookiePresent 请求携带了指定Cookie
+func CookiePresent(name string) func(request *ginstarter.Request) bool {
+	return func(request *ginstarter.Request) bool {
+		value, err := request.GetCookie(name)
+		return err == nil && value != ""
+	}
+}
+
+// BearerPresent 请求携带了Bearer令牌
+func BearerPresent(request *ginstarter.Request) bool {
+	return BearerToken(request) != ""
+}
+
+// BearerToken 获取请求头 Authorization: Bearer <token> 中的令牌
+func BearerToken(request *ginstarter.Request) string {
+	authorization := request.GetHeader("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// GetAuthScheme 获取认证信息的认证方式 仅组合认证方式返回的认证信息含有该信息
+func GetAuthScheme[ID IDType](authority Authority[ID]) (AuthScheme, bool) {
+	schemeAuthority, ok := AuthorityAs[*SchemeAuthority[ID]](authority)
+	if !ok {
+		return "", false
+	}
+	return schemeAuthority.GetScheme(), true
+}
+
+// checkAuthScheme 检查认证信息的认证方式是否被接受
+func checkAuthScheme[ID IDType](authority Authority[ID], schemes []AuthScheme) bool {
+	if len(schemes) == 0 {
+		return true
+	}
+	scheme, ok := GetAuthScheme(authority)
+	return ok && coll.SliceContains(schemes, scheme)
+}
@@ -0,0 +1,131 @@
+package webcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// chainFetch 组合bearer与APIKey认证 令牌"bad"认证失败 每次执行的认证方式写入executed
+func chainFetch(executed *[]AuthScheme) AuthorityFetch[int64] {
+	return NewChainAuthorityFetch[int64](
+		AuthSchemeFetch[int64]{Scheme: AuthSchemeBearer, Match: BearerPresent, Fetch: func(request *ginstarter.Request) Authority[int64] {
+			*executed = append(*executed, AuthSchemeBearer)
+			if BearerToken(request) == "bad" {
+				return nil
+			}
+			return &testAuthority{id: 1}
+		}},
+		AuthSchemeFetch[int64]{Scheme: AuthSchemeAPIKey, Match: HeaderPresent(defaultAPIKeyHeader), Fetch: func(request *ginstarter.Request) Authority[int64] {
+			*executed = append(*executed, AuthSchemeAPIKey)
+			return &testAuthority{id: 2}
+		}},
+		AuthSchemeFetch[int64]{Scheme: "fallback", Fetch: func(request *ginstarter.Request) Authority[int64] {
+			*executed = append(*executed, "fallback")
+			if request.GetHeader("X-Fallback") == "" {
+				return nil
+			}
+			return &testAuthority{id: 3}
+		}},
+	)
+}
+
+func TestChainAuthorityFetchOrder(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   map[string]string
+		scheme   AuthScheme
+		id       int64
+		executed []AuthScheme
+	}{
+		{"first match wins", map[string]string{"Authorization": "Bearer t", defaultAPIKeyHeader: "k"}, AuthSchemeBearer, 1, []AuthScheme{AuthSchemeBearer}},
+		{"failed scheme falls through", map[string]string{"Authorization": "Bearer bad", defaultAPIKeyHeader: "k"}, AuthSchemeAPIKey, 2, []AuthScheme{AuthSchemeBearer, AuthSchemeAPIKey}},
+		{"unmatched schemes skipped", map[string]string{defaultAPIKeyHeader: "k"}, AuthSchemeAPIKey, 2, []AuthScheme{AuthSchemeAPIKey}},
+		{"nil match always tried", map[string]string{"X-Fallback": "1"}, "fallback", 3, []AuthScheme{"fallback"}},
+		{"all failed", map[string]string{"Authorization": "Bearer bad"}, "", 0, []AuthScheme{AuthSchemeBearer, "fallback"}},
+	}
+	for _, c := range cases {
+		var executed []AuthScheme
+		fetch := chainFetch(&executed)
+		handler := func(request *ginstarter.Request) (ginstarter.Response, error) {
+			authority := fetch(request)
+			scheme, _ := GetAuthScheme(authority)
+			var id int64
+			if authority != nil {
+				id = authority.GetIdentityID()
+			}
+			if scheme != c.scheme || id != c.id {
+				t.Errorf("%s: scheme %q identity %d, want %q %d", c.name, scheme, id, c.scheme, c.id)
+			}
+			return ginstarter.RespRestSuccess(), nil
+		}
+		serve(t, handler, testCall{method: http.MethodGet, header: c.header})
+		if len(executed) != len(c.executed) {
+			t.Errorf("%s: executed %v, want %v", c.name, executed, c.executed)
+			continue
+		}
+		for i := range executed {
+			if executed[i] != c.executed[i] {
+				t.Errorf("%s: executed %v, want %v", c.name, executed, c.executed)
+				break
+			}
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	cases := map[string]string{
+		"Bearer abc":   "abc",
+		"bearer  abc ": "abc",
+		"Basic abc":    "",
+		"Bearer ":      "",
+		"":             "",
+	}
+	for authorization, token := range cases {
+		handler := func(request *ginstarter.Request) (ginstarter.Response, error) {
+			if actual := BearerToken(request); actual != token {
+				t.Errorf("BearerToken(%q) = %q, want %q", authorization, actual, token)
+			}
+			return ginstarter.RespRestSuccess(), nil
+		}
+		serve(t, handler, testCall{method: http.MethodGet, header: map[string]string{"Authorization": authorization}})
+	}
+}
+
+func TestAcceptAuthSchemes(t *testing.T) {
+	var executed []AuthScheme
+	router := NewBaseRouterWithAuthority[int64, testRecord, testRecord, testRecord, testRecord](
+		newMemoryBizService(testRecord{ID: 1, UserID: 1}, testRecord{ID: 2, UserID: 2}), chainFetch(&executed), "UserID").
+		AcceptAuthSchemes([]AuthScheme{AuthSchemeAPIKey}, OperationRemoveByID)
+	current := func(request *ginstarter.Request) Authority[int64] { return router.GetAuthorityData(request) }
+	bearer := map[string]string{"Authorization": "Bearer t"}
+	apiKey := map[string]string{defaultAPIKeyHeader: "k"}
+	cases := []struct {
+		name      string
+		operation Operation
+		handler   ginstarter.HandlerWrapper
+		header    map[string]string
+		status    ginstarter.StatusCode
+	}{
+		{"not restricted", OperationQueryByID, router.QueryById(), bearer, ginstarter.StatusCodeSuccess},
+		{"scheme rejected", OperationRemoveByID, router.RemoveById(), bearer, ginstarter.StatusCodeUnauthorized},
+		{"scheme accepted", OperationRemoveByID, router.RemoveById(), apiKey, ginstarter.StatusCodeSuccess},
+		{"custom handler rejected", "", router.WithAuthSchemes(fetchHandler(current), AuthSchemeHMAC), apiKey, ginstarter.StatusCodeUnauthorized},
+		{"custom handler accepted", "", router.WithAuthSchemes(fetchHandler(current), AuthSchemeBearer), bearer, ginstarter.StatusCodeSuccess},
+	}
+	for _, c := range cases {
+		method := http.MethodGet
+		if c.operation == OperationRemoveByID {
+			method = http.MethodDelete
+		}
+		id := "1"
+		if c.header[defaultAPIKeyHeader] != "" {
+			id = "2"
+		}
+		result := serve(t, c.handler, testCall{method: method, params: map[string]string{"id": id}, header: c.header})
+		if result.status != c.status {
+			t.Errorf("%s: status %d %s, want %d", c.name, result.status, result.message, c.status)
+		}
+	}
+}
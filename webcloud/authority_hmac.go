@@ -0,0 +1,234 @@
+package webcloud
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	defaultHMACAccessKeyHeader = "X-Access-Key"
+	defaultHMACTimestampHeader = "X-Timestamp"
+	defaultHMACNonceHeader     = "X-Nonce"
+	defaultHMACSignatureHeader = "X-Signature"
+	defaultHMACMaxClockSkew    = 5 * time.Minute
+)
+
+// HMACCredential HMAC签名凭证
+type HMACCredential[ID IDType] struct {
+	AccessKey  string
+	Secret     string // 签名密钥
+	IdentityID ID
+	Platform   Platform
+	Scopes     []string // 该凭证被授予的权限范围
+	Disabled   bool     // 是否已禁用
+}
+
+// HMACCredentialStore HMAC凭证存储
+type HMACCredentialStore[ID IDType] interface {
+	// FindByAccessKey 通过AccessKey查询凭证 凭证不存在时返回nil
+	FindByAccessKey(accessKey string) (*HMACCredential[ID], error)
+}
+
+// MemoryHMACCredentialStore 基于内存的HMAC凭证存储
+type MemoryHMACCredentialStore[ID IDType] struct {
+	mutex       sync.RWMutex
+	credentials map[string]*HMACCredential[ID]
+}
+
+// NewMemoryHMACCredentialStore 创建基于内存的HMAC凭证存储
+func NewMemoryHMACCredentialStore[ID IDType]() *MemoryHMACCredentialStore[ID] {
+	return &MemoryHMACCredentialStore[ID]{
+		credentials: make(map[string]*HMACCredential[ID]),
+	}
+}
+
+// Add 添加凭证
+func (m *MemoryHMACCredentialStore[ID]) Add(credential *HMACCredential[ID]) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.credentials[credential.AccessKey] = credential
+}
+
+// Remove 移除凭证
+func (m *MemoryHMACCredentialStore[ID]) Remove(accessKey string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.credentials, accessKey)
+}
+
+func (m *MemoryHMACCredentialStore[ID]) FindByAccessKey(accessKey string) (*HMACCredential[ID], error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.credentials[accessKey], nil
+}
+
+// NonceStore 请求随机数存储 用于防重放校验
+type NonceStore interface {
+	// UseOnce 标记nonce已被使用 如果nonce在有效期内已被使用过则返回false
+	UseOnce(nonce string, ttl time.Duration) bool
+}
+
+// MemoryNonceStore 基于内存的随机数存储
+type MemoryNonceStore struct {
+	mutex     sync.Mutex
+	nonces    map[string]time.Time
+	lastClean time.Time
+}
+
+// NewMemoryNonceStore 创建基于内存的随机数存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]time.Time),
+		lastClean: time.Now(),
+	}
+}
+
+func (m *MemoryNonceStore) UseOnce(nonce string, ttl time.Duration) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	// 周期性清理过期的nonce
+	if now.Sub(m.lastClean) > ttl {
+		for k, expireAt := range m.nonces {
+			if now.After(expireAt) {
+				delete(m.nonces, k)
+			}
+		}
+		m.lastClean = now
+	}
+	if expireAt, ok := m.nonces[nonce]; ok && now.Before(expireAt) {
+		return false
+	}
+	m.nonces[nonce] = now.Add(ttl)
+	return true
+}
+
+// HMACFetchConfig HMAC签名认证配置
+type HMACFetchConfig struct {
+	AccessKeyHeader string        // 默认 X-Access-Key
+	TimestampHeader string        // 默认 X-Timestamp 毫秒时间戳
+	NonceHeader     string        // 默认 X-Nonce
+	SignatureHeader string        // 默认 X-Signature
+	MaxClockSkew    time.Duration // 允许的最大时间偏差 默认5分钟
+	NonceStore      NonceStore    // 防重放随机数存储 默认使用内存存储
+	SignedHeaders   []string      // 参与签名的请求头 默认 X-Act-As 代理身份等影响授权的请求头均应参与签名
+	MaxBodyBytes    int64         // 参与签名的请求体最大字节数 经由基础路由时不超过其请求体限制 默认1MB 超出时认证失败
+}
+
+// HMACStringToSign 构造待签名字符串
+// 格式: METHOD\nRequestURI\nTimestamp\nNonce\nSignedHeaders\nHex(SHA256(body))
+// SignedHeaders 为按 signedHeaders 顺序的 小写请求头名:值 以;连接 请求头不存在时值为空
+func HMACStringToSign(method, requestURI, timestamp, nonce string, header http.Header, signedHeaders []string, body []byte) string {
+	digest := sha256.Sum256(body)
+	headers := make([]string, len(signedHeaders))
+	for i, name := range signedHeaders {
+		headers[i] = strings.ToLower(name) + ":" + strings.TrimSpace(header.Get(name))
+	}
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		strings.Join(headers, ";"),
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+}
+
+// HMACSign 计算签名 Hex(HMAC-SHA256(secret, stringToSign))
+func HMACSign(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewHMACAuthorityFetch 创建基于HMAC请求签名的认证方式
+// 请求方使用密钥对 请求方法、请求路径、时间戳、随机数、参与签名的请求头及body摘要 签名 服务端校验签名、时间偏差并拒绝重复的随机数
+// 认证成功后返回 *ClientAuthority
+func NewHMACAuthorityFetch[ID IDType](store HMACCredentialStore[ID], config ...HMACFetchConfig) AuthorityFetch[ID] {
+	var c HMACFetchConfig
+	if len(config) > 0 {
+		c = config[0]
+	}
+	if c.AccessKeyHeader == "" {
+		c.AccessKeyHeader = defaultHMACAccessKeyHeader
+	}
+	if c.TimestampHeader == "" {
+		c.TimestampHeader = defaultHMACTimestampHeader
+	}
+	if c.NonceHeader == "" {
+		c.NonceHeader = defaultHMACNonceHeader
+	}
+	if c.SignatureHeader == "" {
+		c.SignatureHeader = defaultHMACSignatureHeader
+	}
+	if c.MaxClockSkew <= 0 {
+		c.MaxClockSkew = defaultHMACMaxClockSkew
+	}
+	if c.NonceStore == nil {
+		c.NonceStore = NewMemoryNonceStore()
+	}
+	if c.SignedHeaders == nil {
+		c.SignedHeaders = []string{defaultActAsHeader}
+	}
+	return func(request *ginstarter.Request) Authority[ID] {
+		accessKey := request.GetHeader(c.AccessKeyHeader)
+		timestamp := request.GetHeader(c.TimestampHeader)
+		nonce := request.GetHeader(c.NonceHeader)
+		signature := request.GetHeader(c.SignatureHeader)
+		if accessKey == "" || timestamp == "" || nonce == "" || signature == "" {
+			return nil
+		}
+		millis, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			logger.Logrus().Warningln("bad hmac timestamp:", timestamp)
+			return nil
+		}
+		skew := time.Since(time.UnixMilli(millis))
+		if skew > c.MaxClockSkew || skew < -c.MaxClockSkew {
+			logger.Logrus().Warningln("hmac timestamp out of range, access key:", accessKey)
+			return nil
+		}
+		credential, err := store.FindByAccessKey(accessKey)
+		if err != nil {
+			logger.Logrus().Errorln("find hmac credential error:", err)
+			return nil
+		}
+		if credential == nil || credential.Disabled {
+			logger.Logrus().Warningln("unknown or disabled hmac access key:", accessKey)
+			return nil
+		}
+		// 请求体超出限制时在校验签名前拒绝
+		body, err := readRawBody(request, requestBodyLimit(request, c.MaxBodyBytes))
+		if err != nil {
+			logger.Logrus().Warningln("read request body error:", err)
+			return nil
+		}
+		stringToSign := HMACStringToSign(request.HttpMethod(), request.RequestFullPath(), timestamp, nonce,
+			request.RawGinContext().Request.Header, c.SignedHeaders, body)
+		expected := HMACSign(credential.Secret, stringToSign)
+		if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+			logger.Logrus().Warningln("bad hmac signature, access key:", accessKey)
+			return nil
+		}
+		// 签名校验通过后再占用nonce 避免伪造请求消耗合法nonce
+		if !c.NonceStore.UseOnce(accessKey+":"+nonce, 2*c.MaxClockSkew) {
+			logger.Logrus().Warningln("hmac nonce replayed, access key:", accessKey)
+			return nil
+		}
+		return &ClientAuthority[ID]{
+			IdentityID: credential.IdentityID,
+			Platform:   credential.Platform,
+			Scopes:     credential.Scopes,
+			AccessKey:  accessKey,
+		}
+	}
+}
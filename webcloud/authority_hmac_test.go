@@ -0,0 +1,108 @@
+package webcloud
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACStringToSignSignedHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Act-As", " 42 ")
+	signed := []string{"X-Act-As", "X-Tenant"}
+	stringToSign := HMACStringToSign("post", "/user/save?a=1", "1700000000000", "n1", header, signed, []byte(`{}`))
+	lines := strings.Split(stringToSign, "\n")
+	if len(lines) != 6 {
+		t.Fatalf("unexpected string to sign: %q", stringToSign)
+	}
+	if lines[0] != "POST" || lines[4] != "x-act-as:42;x-tenant:" {
+		t.Fatalf("unexpected string to sign: %q", stringToSign)
+	}
+	other := http.Header{}
+	other.Set("X-Act-As", "43")
+	if HMACSign("secret", stringToSign) == HMACSign("secret", HMACStringToSign("POST", "/user/save?a=1", "1700000000000", "n1", other, signed, []byte(`{}`))) {
+		t.Fatal("changing a signed header should change the signature")
+	}
+}
+
+// hmacCall 构造签名请求 timestamp 为签名使用的时间
+func hmacCall(secret, nonce string, timestamp time.Time, body string) testCall {
+	millis := strconv.FormatInt(timestamp.UnixMilli(), 10)
+	stringToSign := HMACStringToSign(http.MethodPost, "/test/hmac", millis, nonce, http.Header{}, []string{defaultActAsHeader}, []byte(body))
+	return testCall{path: "hmac", body: body, header: map[string]string{
+		defaultHMACAccessKeyHeader: "ak",
+		defaultHMACTimestampHeader: millis,
+		defaultHMACNonceHeader:     nonce,
+		defaultHMACSignatureHeader: HMACSign(secret, stringToSign),
+	}}
+}
+
+func hmacStore() *MemoryHMACCredentialStore[int64] {
+	store := NewMemoryHMACCredentialStore[int64]()
+	store.Add(&HMACCredential[int64]{AccessKey: "ak", Secret: "secret", IdentityID: 1})
+	return store
+}
+
+func TestHMACNonceReplay(t *testing.T) {
+	fetch := NewHMACAuthorityFetch[int64](hmacStore())
+	now := time.Now()
+	if id := fetchIdentity(t, fetch, hmacCall("secret", "n1", now, `{"a":1}`)); id != 1 {
+		t.Fatalf("valid request rejected: %d", id)
+	}
+	if id := fetchIdentity(t, fetch, hmacCall("secret", "n1", now, `{"a":1}`)); id != 0 {
+		t.Fatal("replayed nonce accepted")
+	}
+	// 签名错误的请求不占用nonce
+	if id := fetchIdentity(t, fetch, hmacCall("wrong", "n2", now, `{}`)); id != 0 {
+		t.Fatal("bad signature accepted")
+	}
+	if id := fetchIdentity(t, fetch, hmacCall("secret", "n2", now, `{}`)); id != 1 {
+		t.Fatal("nonce consumed by a request with bad signature")
+	}
+	tampered := hmacCall("secret", "n3", now, `{"a":1}`)
+	tampered.body = `{"a":2}`
+	if id := fetchIdentity(t, fetch, tampered); id != 0 {
+		t.Fatal("tampered body accepted")
+	}
+}
+
+func TestHMACClockSkew(t *testing.T) {
+	fetch := NewHMACAuthorityFetch[int64](hmacStore(), HMACFetchConfig{MaxClockSkew: time.Minute})
+	cases := []struct {
+		name   string
+		offset time.Duration
+		id     int64
+	}{
+		{"in range past", -30 * time.Second, 1},
+		{"in range future", 30 * time.Second, 1},
+		{"too old", -2 * time.Minute, 0},
+		{"too far in future", 2 * time.Minute, 0},
+	}
+	for i, c := range cases {
+		call := hmacCall("secret", "skew"+strconv.Itoa(i), time.Now().Add(c.offset), "")
+		if id := fetchIdentity(t, fetch, call); id != c.id {
+			t.Errorf("%s: identity %d, want %d", c.name, id, c.id)
+		}
+	}
+}
+
+func TestHMACDisabledCredential(t *testing.T) {
+	store := hmacStore()
+	store.Add(&HMACCredential[int64]{AccessKey: "ak", Secret: "secret", IdentityID: 1, Disabled: true})
+	if id := fetchIdentity(t, NewHMACAuthorityFetch[int64](store), hmacCall("secret", "n1", time.Now(), "")); id != 0 {
+		t.Fatal("disabled credential accepted")
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	store := NewMemoryNonceStore()
+	if !store.UseOnce("n", 10*time.Millisecond) || store.UseOnce("n", 10*time.Millisecond) {
+		t.Fatal("nonce should be usable exactly once within ttl")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !store.UseOnce("n", 10*time.Millisecond) {
+		t.Fatal("expired nonce should be usable again")
+	}
+}
@@ -0,0 +1,196 @@
+package webcloud
+
+import (
+	"bytes"
+	sdkjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	defaultMaxBodyBytes = 1 << 20
+	defaultMaxBodyDepth = 32
+)
+
+const ctxKeyBodyLimit = "_webcloud_body_limit"
+
+// BodyLimit 请求体限制
+type BodyLimit struct {
+	MaxBytes int64 // 请求体最大字节数 默认1MB
+	MaxDepth int   // json最大嵌套深度 默认32
+}
+
+// BodyError 请求体有误 响应参数错误并携带错误详情
+type BodyError struct {
+	Offset int64  // 出错位置的字节偏移
+	Reason string // 错误原因
+}
+
+func (e *BodyError) Error() string {
+	return fmt.Sprintf("%s (at byte offset %d)", e.Reason, e.Offset)
+}
+
+// SetBodyLimit 设置请求体的大小与json嵌套深度限制
+func (b *BaseRouter[ID, S, M, Q, D]) SetBodyLimit(limit BodyLimit) *BaseRouter[ID, S, M, Q, D] {
+	b.bodyLimit = limit
+	return b
+}
+
+// bodyLimits 获取生效的请求体限制
+func (b *BaseRouter[ID, S, M, Q, D]) bodyLimits() BodyLimit {
+	limit := b.bodyLimit
+	if limit.MaxBytes <= 0 {
+		limit.MaxBytes = defaultMaxBodyBytes
+	}
+	if limit.MaxDepth <= 0 {
+		limit.MaxDepth = defaultMaxBodyDepth
+	}
+	return limit
+}
+
+// readBody 读取请求体 超出大小限制时返回 BodyError 读取后回填body以便后续流程再次读取
+func (b *BaseRouter[ID, S, M, Q, D]) readBody(request *ginstarter.Request) ([]byte, error) {
+	return readRawBody(request, b.bodyLimits().MaxBytes)
+}
+
+// requestBodyLimit 获取当前请求的请求体大小限制 limit为认证方式等自身设置的限制
+// 请求经由基础路由时取两者中较小的限制 均未设置时使用默认限制
+func requestBodyLimit(request *ginstarter.Request, limit int64) int64 {
+	if v, ok := request.GetValue(ctxKeyBodyLimit); ok {
+		if routerLimit, ok := v.(int64); ok && routerLimit > 0 && (limit <= 0 || routerLimit < limit) {
+			limit = routerLimit
+		}
+	}
+	if limit <= 0 {
+		limit = defaultMaxBodyBytes
+	}
+	return limit
+}
+
+// setRawBody 缓存并回填请求体
+func setRawBody(request *ginstarter.Request, body []byte) {
+	ctx := request.RawGinContext()
+	ctx.Set(gin.BodyBytesKey, body)
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	ctx.Request.ContentLength = int64(len(body))
+}
+
+// readJsonObject 读取请求体并解析为json对象
+func (b *BaseRouter[ID, S, M, Q, D]) readJsonObject(request *ginstarter.Request) (map[string]any, error) {
+	body, err := b.readBody(request)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJsonObject(body, b.bodyLimits().MaxDepth)
+}
+
+// bindJson 读取请求体并绑定至结构体 按请求体限制检查并执行结构体校验 请求体有误时返回 BodyError
+func (b *BaseRouter[ID, S, M, Q, D]) bindJson(request *ginstarter.Request, ptr any) error {
+	body, err := b.readBody(request)
+	if err != nil {
+		return err
+	}
+	if _, err = decodeJsonObject(body, b.bodyLimits().MaxDepth); err != nil {
+		return err
+	}
+	decoder := sdkjson.NewDecoder(bytes.NewReader(body))
+	if err = decoder.Decode(ptr); err != nil {
+		return jsonBodyError(err, int64(len(body)))
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(ptr)
+}
+
+// decodeJsonObject 解析json对象 数值解析为json.Number 语法错误 嵌套过深或非对象时返回 BodyError
+func decodeJsonObject(data []byte, maxDepth int) (map[string]any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, &BodyError{Offset: 0, Reason: "empty request body"}
+	}
+	if err := checkJsonDepth(data, maxDepth); err != nil {
+		return nil, err
+	}
+	decoder := sdkjson.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result map[string]any
+	if err := decoder.Decode(&result); err != nil {
+		return nil, jsonBodyError(err, int64(len(data)))
+	}
+	offset := decoder.InputOffset()
+	if rest := bytes.TrimLeft(data[offset:], " \t\r\n"); len(rest) > 0 {
+		offset += int64(len(data[offset:]) - len(rest))
+		return nil, &BodyError{Offset: offset, Reason: "invalid json: unexpected data after top-level value"}
+	}
+	if result == nil {
+		return nil, &BodyError{Offset: 0, Reason: "request body must be a json object"}
+	}
+	return result, nil
+}
+
+// checkJsonDepth 检查首个json值的嵌套深度 语法错误交由解析流程返回
+func checkJsonDepth(data []byte, maxDepth int) error {
+	decoder := sdkjson.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		switch token {
+		case sdkjson.Delim('{'), sdkjson.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return &BodyError{Offset: decoder.InputOffset() - 1, Reason: fmt.Sprintf("json nesting depth exceeds %d", maxDepth)}
+			}
+		case sdkjson.Delim('}'), sdkjson.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonBodyError 将json解析错误转换为 BodyError offset为无法确定位置时使用的偏移 通常为请求体长度
+func jsonBodyError(err error, offset int64) error {
+	var syntaxErr *sdkjson.SyntaxError
+	var typeErr *sdkjson.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// 非法字符的错误偏移包含该字符 转换为该字符所在的位置
+		offset = syntaxErr.Offset
+		if strings.HasPrefix(syntaxErr.Error(), "invalid character") && offset > 0 {
+			offset--
+		}
+		return &BodyError{Offset: offset, Reason: "invalid json: " + syntaxErr.Error()}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return &BodyError{Offset: typeErr.Offset, Reason: "request body must be a json object"}
+		}
+		return &BodyError{Offset: typeErr.Offset, Reason: fmt.Sprintf("invalid json: field %s cannot be %s", typeErr.Field, typeErr.Value)}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &BodyError{Offset: offset, Reason: "invalid json: unexpected end of json input"}
+	default:
+		return &BodyError{Offset: offset, Reason: "invalid json: " + err.Error()}
+	}
+}
+
+// bodyErrorResponse 请求体有误时响应参数错误 BodyError NumberFieldError 携带错误详情
+func bodyErrorResponse(err error) ginstarter.Response {
+	var bodyErr *BodyError
+	var numberErr *NumberFieldError
+	if errors.As(err, &bodyErr) {
+		return ginstarter.RespRestBadParameters(bodyErr.Error())
+	}
+	if errors.As(err, &numberErr) {
+		return ginstarter.RespRestBadParameters(numberErr.Error())
+	}
+	return ginstarter.RespRestBadParameters()
+}
@@ -0,0 +1,63 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJsonObject(t *testing.T) {
+	object, err := decodeJsonObject([]byte(` {"id": 9223372036854775808, "tags": ["a"], "nested": {"x": 1.5}} `), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object["id"] != sdkjson.Number("9223372036854775808") {
+		t.Fatalf("number precision lost: %#v", object["id"])
+	}
+	if nested, ok := object["nested"].(map[string]any); !ok || nested["x"] != sdkjson.Number("1.5") {
+		t.Fatalf("unexpected nested value: %#v", object["nested"])
+	}
+}
+
+func TestDecodeJsonObjectErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		maxDepth int
+		offset   int64
+		reason   string
+	}{
+		{"empty", "", 32, 0, "empty request body"},
+		{"blank", " \n ", 32, 0, "empty request body"},
+		{"too deep", `{"a":{"b":{"c":1}}}`, 2, 10, "json nesting depth exceeds 2"},
+		{"too deep array", `{"a":[[1]]}`, 2, 6, "json nesting depth exceeds 2"},
+		{"trailing data", `{"a":1} x`, 32, 8, "unexpected data after top-level value"},
+		{"second object", `{"a":1}{"b":2}`, 32, 7, "unexpected data after top-level value"},
+		{"array", `[1,2]`, 32, -1, "request body must be a json object"},
+		{"string", `"text"`, 32, -1, "request body must be a json object"},
+		{"null", `null`, 32, 0, "request body must be a json object"},
+		{"trailing comma", `{"a":1,}`, 32, 7, "invalid character '}'"},
+		{"missing colon", `{"a" 1}`, 32, 5, "invalid character '1'"},
+		{"bad literal", `{"a":tru}`, 32, 8, "invalid character '}'"},
+		{"unterminated", `{"a":`, 32, 5, "unexpected end of json input"},
+		{"syntax before depth", `{"a":[}, [[[[1]]]]]`, 2, 6, "invalid character '}'"},
+	}
+	for _, c := range cases {
+		_, err := decodeJsonObject([]byte(c.body), c.maxDepth)
+		var bodyErr *BodyError
+		if !errors.As(err, &bodyErr) {
+			t.Errorf("%s: expected BodyError, got %v", c.name, err)
+			continue
+		}
+		if !strings.Contains(bodyErr.Reason, c.reason) {
+			t.Errorf("%s: reason %q, want %q", c.name, bodyErr.Reason, c.reason)
+		}
+		if c.offset >= 0 && bodyErr.Offset != c.offset {
+			t.Errorf("%s: offset %d, want %d", c.name, bodyErr.Offset, c.offset)
+		}
+		if bodyErr.Offset < 0 || bodyErr.Offset > int64(len(c.body)) {
+			t.Errorf("%s: offset %d out of body", c.name, bodyErr.Offset)
+		}
+	}
+}
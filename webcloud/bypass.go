@@ -0,0 +1,27 @@
+package webcloud
+
+import (
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const ctxKeyBypassAudited = "_webcloud_bypass_audited"
+
+// SetBypassPermission 设置跳过数据权限控制所需的权限 认证信息需实现 PermissionAuthority
+// 拥有该权限的认证信息在同一路由中可访问本租户的全部数据 租户隔离不受影响 每次跳过均会记录审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) SetBypassPermission(permission string) *BaseRouter[ID, S, M, Q, D] {
+	b.bypassPermission = permission
+	return b
+}
+
+// bypassDataLimit 判断当前请求是否跳过数据权限控制 跳过时记录审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) bypassDataLimit(request *ginstarter.Request, authority Authority[ID]) bool {
+	if b.bypassPermission == "" || !HasPermission(authority, b.bypassPermission) {
+		return false
+	}
+	// 同一请求仅记录一次
+	if _, audited := request.GetValue(ctxKeyBypassAudited); !audited {
+		request.SetValue(ctxKeyBypassAudited, true)
+		b.audit(b.newAuditEvent(request, AuditActionBypass, authority))
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package webcloud
+
+import "testing"
+
+func TestBypassKeepsTenantOnSave(t *testing.T) {
+	router := &testRouter{tenantColumn: "tenant_id"}
+	authority := &testAuthority{id: 1, tenantID: int64(10)}
+	values, pass := router.limitValues(authority, "user_id", false, true)
+	if !pass {
+		t.Fatal("bypass with tenant should pass")
+	}
+	if _, ok := values["user_id"]; ok {
+		t.Fatalf("bypass should not stamp owner: %v", values)
+	}
+	param := testRecord{UserID: 2, TenantID: 99}
+	if err := setStructColumns(&param, values); err != nil {
+		t.Fatal(err)
+	}
+	if param.TenantID != 10 {
+		t.Fatalf("client supplied tenant kept: %d", param.TenantID)
+	}
+	if param.UserID != 2 {
+		t.Fatalf("bypass should keep client supplied owner: %d", param.UserID)
+	}
+}
+
+func TestBypassKeepsTenantOnQuery(t *testing.T) {
+	router := &testRouter{tenantColumn: "tenant_id"}
+	authority := &testAuthority{id: 1, tenantID: int64(10)}
+	values, pass := router.limitValues(authority, "user_id", false, false)
+	if !pass {
+		t.Fatal("bypass with tenant should pass")
+	}
+	param := map[string]any{"id": int64(5), "tenant_id": int64(99)}
+	if mergeConditions(param, values) {
+		t.Fatal("bypass should not allow access to another tenant")
+	}
+	if _, ok := param["user_id"]; ok {
+		t.Fatalf("bypass should not limit owner: %v", param)
+	}
+}
+
+func TestBypassRequiresTenant(t *testing.T) {
+	router := &testRouter{tenantColumn: "tenant_id"}
+	if _, pass := router.limitValues(&testAuthority{id: 1}, "user_id", false, false); pass {
+		t.Fatal("authority without tenant should be rejected even when bypassing")
+	}
+}
+
+func TestNoBypassStampsOwnerAndTenant(t *testing.T) {
+	router := &testRouter{tenantColumn: "tenant_id"}
+	values, pass := router.limitValues(&testAuthority{id: 1, tenantID: int64(10)}, "user_id", true, false)
+	if !pass || values["user_id"] != int64(1) || values["tenant_id"] != int64(10) {
+		t.Fatalf("unexpected limit values: %v %v", values, pass)
+	}
+}
@@ -0,0 +1,112 @@
+package webcloud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+)
+
+// flightCall 执行中的合并调用
+type flightCall[D any] struct {
+	done   chan struct{}
+	result D
+	row    int64
+	err    error
+}
+
+// flightGroup 合并相同key的并发调用 仅执行一次并将结果分发至全部调用方
+type flightGroup[D any] struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall[D]
+}
+
+// do 执行调用 相同key的调用仅执行一次 fn在独立的goroutine中以不随调用方取消的上下文执行 timeout大于0时按其超时
+// 每个调用方均可因各自的ctx取消提前返回
+func (g *flightGroup[D]) do(ctx context.Context, key string, timeout time.Duration, fn func(ctx context.Context) (D, int64, error)) (result D, row int64, err error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[D])
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &flightCall[D]{done: make(chan struct{})}
+		g.calls[key] = call
+		callCtx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if timeout > 0 {
+			callCtx, cancel = context.WithTimeout(callCtx, timeout)
+		}
+		go g.run(callCtx, cancel, key, call, fn)
+	}
+	g.mutex.Unlock()
+	select {
+	case <-call.done:
+		return call.result, call.row, call.err
+	case <-ctx.Done():
+		return result, 0, ctx.Err()
+	}
+}
+
+// run 执行合并的调用并通知全部调用方 panic转换为错误返回
+func (g *flightGroup[D]) run(ctx context.Context, cancel context.CancelFunc, key string, call *flightCall[D], fn func(ctx context.Context) (D, int64, error)) {
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Logrus().Errorln("coalesced call panic:", key, r)
+			call.err = fmt.Errorf("coalesced call panic: %v", r)
+		}
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+	}()
+	call.result, call.row, call.err = fn(ctx)
+}
+
+// EnableReadCoalescing 启用主键查询的请求合并 相同认证信息以相同主键与相同数据权限条件的并发查询仅调用一次业务服务
+// 合并的查询使用不随请求取消的上下文执行 设置了主键查询的超时限制时按该超时取消 各请求仍可因自身取消或超时提前返回
+// 响应数据的脱敏仍按各请求的认证信息处理
+func (b *BaseRouter[ID, S, M, Q, D]) EnableReadCoalescing() *BaseRouter[ID, S, M, Q, D] {
+	b.readFlight = &flightGroup[D]{}
+	return b
+}
+
+// queryByIDCoalesced 查询单条数据 启用请求合并时合并相同条件的并发查询
+func (b *BaseRouter[ID, S, M, Q, D]) queryByIDCoalesced(ctx context.Context, condition map[string]any) (D, int64, error) {
+	query := func(ctx context.Context) (D, int64, error) {
+		var d D
+		row, err := b.bizService.BaseQueryByIDContext(ctx, condition, &d)
+		return d, row, err
+	}
+	if b.readFlight == nil {
+		return query(ctx)
+	}
+	var timeout time.Duration
+	if limiter := b.operationLimits[OperationQueryByID]; limiter != nil {
+		timeout = limiter.Timeout
+	}
+	// 条件中已包含数据权限控制与策略附加的条件 fmt按key排序输出map
+	// 合并的查询以首个请求的上下文执行 仅合并认证信息相同的请求 业务服务获取的认证信息始终为请求方自身
+	return b.readFlight.do(ctx, b.resource+":"+authorityKey[ID](ctx)+":"+fmt.Sprint(condition), timeout, query)
+}
+
+// authorityKey 上下文中认证信息的标识 包含身份、平台、租户、数据范围及代理时的实际身份
+func authorityKey[ID IDType](ctx context.Context) string {
+	authority, ok := AuthorityFromContext[ID](ctx)
+	if !ok {
+		return ""
+	}
+	key := fmt.Sprint(authority.GetPlatform(), "/", authority.GetIdentityID())
+	if tenantID, ok := GetTenantID(authority); ok {
+		key += fmt.Sprint("/tenant=", tenantID)
+	}
+	if scopeAuthority, ok := AuthorityAs[DataScopeAuthority](authority); ok {
+		key += fmt.Sprint("/scope=", scopeAuthority.GetDataScope())
+	}
+	if impersonated, ok := authority.(*ImpersonatedAuthority[ID]); ok {
+		key += fmt.Sprint("/real=", impersonated.Real.GetPlatform(), "/", impersonated.Real.GetIdentityID())
+	}
+	return key
+}
@@ -0,0 +1,151 @@
+package webcloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlightGroupLeaderCancelDoesNotCancelCall(t *testing.T) {
+	var group flightGroup[string]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	callErr := make(chan error, 1)
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, _, err := group.do(leaderCtx, "k", 0, func(ctx context.Context) (string, int64, error) {
+			close(started)
+			<-release
+			callErr <- ctx.Err()
+			return "ok", 1, nil
+		})
+		leaderDone <- err
+	}()
+	<-started
+	waiterCtx, cancelWaiter := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWaiter()
+	if _, _, err := group.do(waiterCtx, "k", 0, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiter should stop on its own ctx: %v", err)
+	}
+	cancelLeader()
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader should stop on its own ctx: %v", err)
+	}
+	close(release)
+	if err := <-callErr; err != nil {
+		t.Fatalf("shared call canceled with leader: %v", err)
+	}
+}
+
+func TestFlightGroupTimeout(t *testing.T) {
+	var group flightGroup[string]
+	_, _, err := group.do(context.Background(), "k", 10*time.Millisecond, func(ctx context.Context) (string, int64, error) {
+		<-ctx.Done()
+		return "", 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shared call should be bounded by timeout: %v", err)
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var group flightGroup[string]
+	_, _, err := group.do(context.Background(), "k", 0, func(context.Context) (string, int64, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("panic in shared call should be returned as error")
+	}
+}
+
+// blockingBizService 主键查询阻塞至release关闭 每次调用的认证信息写入entered
+type blockingBizService struct {
+	*memoryBizService
+	entered chan Authority[int64]
+	release chan struct{}
+}
+
+func (b *blockingBizService) BaseQueryByIDContext(ctx context.Context, condition map[string]any, result *testRecord) (int64, error) {
+	authority, _ := AuthorityFromContext[int64](ctx)
+	b.entered <- authority
+	<-b.release
+	return b.memoryBizService.BaseQueryByIDContext(ctx, condition, result)
+}
+
+func TestReadCoalescingByAuthority(t *testing.T) {
+	service := &blockingBizService{
+		memoryBizService: newMemoryBizService(testRecord{ID: 1}),
+		entered:          make(chan Authority[int64], 4),
+		release:          make(chan struct{}),
+	}
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service).EnableReadCoalescing()
+	query := func(authority Authority[int64], done chan<- struct{}) {
+		ctx := context.WithValue(context.Background(), contextKeyAuthority, authority)
+		if _, row, err := router.queryByIDCoalesced(ctx, map[string]any{"id": int64(1)}); err != nil || row != 1 {
+			t.Errorf("coalesced query: %d %v", row, err)
+		}
+		done <- struct{}{}
+	}
+	waitEntered := func() Authority[int64] {
+		select {
+		case authority := <-service.entered:
+			return authority
+		case <-time.After(time.Second):
+			t.Fatal("query not executed")
+			return nil
+		}
+	}
+
+	alice := &testAuthority{id: 1, tenantID: int64(1)}
+	bob := &testAuthority{id: 2, tenantID: int64(1)}
+	done := make(chan struct{}, 4)
+	go query(alice, done)
+	first := waitEntered()
+	go query(bob, done)
+	second := waitEntered()
+	if first != Authority[int64](alice) || second != Authority[int64](bob) {
+		t.Fatalf("each authority should query with its own context: %v %v", first, second)
+	}
+	close(service.release)
+	<-done
+	<-done
+
+	service.release = make(chan struct{})
+	go query(alice, done)
+	waitEntered()
+	go query(&testAuthority{id: 1, tenantID: int64(1)}, done)
+	select {
+	case authority := <-service.entered:
+		t.Fatalf("same authority should share the query: %v", authority)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(service.release)
+	<-done
+	<-done
+}
+
+func TestAuthorityKey(t *testing.T) {
+	keyOf := func(authority Authority[int64]) string {
+		return authorityKey[int64](context.WithValue(context.Background(), contextKeyAuthority, authority))
+	}
+	base := keyOf(&testAuthority{id: 1, tenantID: 1})
+	distinct := []Authority[int64]{
+		&testAuthority{id: 2, tenantID: 1},
+		&testAuthority{id: 1, tenantID: 2},
+		&testAuthority{id: 1, tenantID: 1, scope: &DataScope{Kind: DataScopeCustom, IDs: []any{1}}},
+		&ImpersonatedAuthority[int64]{Authority: &testAuthority{id: 1, tenantID: 1}, Real: &testAuthority{id: 9}},
+	}
+	for _, authority := range distinct {
+		if keyOf(authority) == base {
+			t.Errorf("authority %+v shares key %s", authority, base)
+		}
+	}
+	if keyOf(&SchemeAuthority[int64]{Authority: &testAuthority{id: 1, tenantID: 1}, Scheme: "bearer"}) != base {
+		t.Error("auth scheme should not split coalescing")
+	}
+	if authorityKey[int64](context.Background()) != "" {
+		t.Error("anonymous key should be empty")
+	}
+}
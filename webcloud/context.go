@@ -0,0 +1,156 @@
+package webcloud
+
+import (
+	"context"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	HeaderRequestID     = "X-Request-Id"  // 请求标识
+	HeaderTraceParent   = "traceparent"   // W3C Trace Context
+	HeaderTraceResponse = "traceresponse" // W3C Trace Context Level 2 响应头
+)
+
+type contextKey int8
+
+const (
+	contextKeyAuthority contextKey = iota
+	contextKeyMetadata
+)
+
+// RequestMetadata 传递至业务服务的请求元数据
+type RequestMetadata struct {
+	Resource    string
+	Operation   Operation
+	RequestID   string
+	TraceParent string
+	ClientIP    string
+	Path        string
+}
+
+// BaseBizServiceContext 支持上下文的基础业务服务 BaseBizService 同时实现该接口时基础路由优先使用
+// ctx 随客户端断开或超时取消 并携带认证信息与请求元数据 可通过 AuthorityFromContext MetadataFromContext 获取
+type BaseBizServiceContext[ID IDType, S, D any] interface {
+
+	// SaveContext 保存数据
+	SaveContext(ctx context.Context, save *S) (ID, error)
+
+	// BaseQueryByIDContext 通过主键查询
+	BaseQueryByIDContext(ctx context.Context, condition map[string]any, result *D) (int64, error)
+
+	// BaseQueryOneContext 通过条件查询一条数据
+	BaseQueryOneContext(ctx context.Context, condition map[string]any, result *D) (int64, error)
+
+	// BaseQueryContext 通过条件多条数据
+	BaseQueryContext(ctx context.Context, condition map[string]any, result *[]*D) (int64, error)
+
+	// BaseQueryByPagerContext 分页查询
+	BaseQueryByPagerContext(ctx context.Context, condition map[string]any, pager *Pager[D]) error
+
+	// BaseModifyByIDContext 通过主键修改数据
+	// update 的key为json字段名(小驼峰 与请求字段一致) 修改、回滚、状态迁移均以该形式传入 condition 的key为数据库字段名
+	BaseModifyByIDContext(ctx context.Context, update, condition map[string]any) (int64, error)
+
+	// BaseRemoveByIDContext 通过主键删除数据
+	BaseRemoveByIDContext(ctx context.Context, condition map[string]any) (int64, error)
+}
+
+// bizServiceAdapter 将 BaseBizService 适配为 BaseBizServiceContext 上下文已取消时不再调用业务服务
+// 业务服务不感知上下文 已开始的调用无法随上下文取消中断 上下文仅能通过 BaseBizServiceContext 传递至业务服务
+type bizServiceAdapter[ID IDType, S, M, Q, D any] struct {
+	service BaseBizService[ID, S, M, Q, D]
+}
+
+// AdaptBizService 将业务服务转换为 BaseBizServiceContext 已实现时直接返回
+func AdaptBizService[ID IDType, S, M, Q, D any](service BaseBizService[ID, S, M, Q, D]) BaseBizServiceContext[ID, S, D] {
+	if contextService, ok := service.(BaseBizServiceContext[ID, S, D]); ok {
+		return contextService
+	}
+	return &bizServiceAdapter[ID, S, M, Q, D]{service: service}
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) SaveContext(ctx context.Context, save *S) (ID, error) {
+	if err := ctx.Err(); err != nil {
+		var id ID
+		return id, err
+	}
+	return a.service.Save(save)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseQueryByIDContext(ctx context.Context, condition map[string]any, result *D) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.service.BaseQueryByID(condition, result)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseQueryOneContext(ctx context.Context, condition map[string]any, result *D) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.service.BaseQueryOne(condition, result)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseQueryContext(ctx context.Context, condition map[string]any, result *[]*D) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.service.BaseQuery(condition, result)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseQueryByPagerContext(ctx context.Context, condition map[string]any, pager *Pager[D]) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.service.BaseQueryByPager(condition, pager)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseModifyByIDContext(ctx context.Context, update, condition map[string]any) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.service.BaseModifyByID(update, condition)
+}
+
+func (a *bizServiceAdapter[ID, S, M, Q, D]) BaseRemoveByIDContext(ctx context.Context, condition map[string]any) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return a.service.BaseRemoveByID(condition)
+}
+
+// AuthorityFromContext 获取上下文中的认证信息
+func AuthorityFromContext[ID IDType](ctx context.Context) (Authority[ID], bool) {
+	authority, ok := ctx.Value(contextKeyAuthority).(Authority[ID])
+	return authority, ok && authority != nil
+}
+
+// MetadataFromContext 获取上下文中的请求元数据
+func MetadataFromContext(ctx context.Context) (*RequestMetadata, bool) {
+	metadata, ok := ctx.Value(contextKeyMetadata).(*RequestMetadata)
+	return metadata, ok
+}
+
+// requestContext 获取传递至业务服务的上下文
+func (b *BaseRouter[ID, S, M, Q, D]) requestContext(request *ginstarter.Request) context.Context {
+	ctx := request.RawGinContext().Request.Context()
+	metadata := &RequestMetadata{
+		Resource:    b.resource,
+		RequestID:   request.GetHeader(HeaderRequestID),
+		TraceParent: request.GetHeader(HeaderTraceParent),
+		ClientIP:    request.RequestIP(),
+		Path:        request.RequestPath(),
+	}
+	metadata.Operation, _ = GetOperation(request)
+	if traceParent := TraceParent(ctx); traceParent != "" {
+		metadata.TraceParent = traceParent
+	}
+	ctx = context.WithValue(ctx, contextKeyMetadata, metadata)
+	if b.authorityFetch != nil {
+		if authority := b.GetAuthorityData(request, true); authority != nil {
+			ctx = context.WithValue(ctx, contextKeyAuthority, authority)
+		}
+	}
+	return ctx
+}
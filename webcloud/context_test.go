@@ -0,0 +1,120 @@
+package webcloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// testLegacyBizService 未实现 BaseBizServiceContext 的业务服务 记录调用次数
+type testLegacyBizService struct {
+	testBizService
+	calls int
+}
+
+func (s *testLegacyBizService) Save(*testRecord) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+func (s *testLegacyBizService) BaseQueryByID(map[string]any, *testRecord) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+func (s *testLegacyBizService) BaseQueryOne(map[string]any, *testRecord) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+func (s *testLegacyBizService) BaseQuery(map[string]any, *[]*testRecord) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+func (s *testLegacyBizService) BaseQueryByPager(map[string]any, *Pager[testRecord]) error {
+	s.calls++
+	return nil
+}
+func (s *testLegacyBizService) BaseModifyByID(map[string]any, map[string]any) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+func (s *testLegacyBizService) BaseRemoveByID(map[string]any) (int64, error) {
+	s.calls++
+	return 1, nil
+}
+
+// adapterCalls 以ctx调用适配后业务服务的全部方法 返回各方法的错误
+func adapterCalls(service BaseBizServiceContext[int64, testRecord, testRecord], ctx context.Context) map[string]error {
+	errs := make(map[string]error)
+	_, errs["Save"] = service.SaveContext(ctx, &testRecord{})
+	_, errs["BaseQueryByID"] = service.BaseQueryByIDContext(ctx, map[string]any{"id": 1}, &testRecord{})
+	_, errs["BaseQueryOne"] = service.BaseQueryOneContext(ctx, map[string]any{"id": 1}, &testRecord{})
+	_, errs["BaseQuery"] = service.BaseQueryContext(ctx, map[string]any{}, &[]*testRecord{})
+	errs["BaseQueryByPager"] = service.BaseQueryByPagerContext(ctx, map[string]any{}, &Pager[testRecord]{})
+	_, errs["BaseModifyByID"] = service.BaseModifyByIDContext(ctx, map[string]any{"name": "a"}, map[string]any{"id": 1})
+	_, errs["BaseRemoveByID"] = service.BaseRemoveByIDContext(ctx, map[string]any{"id": 1})
+	return errs
+}
+
+func TestAdaptBizServiceCancelled(t *testing.T) {
+	legacy := &testLegacyBizService{}
+	adapted := AdaptBizService[int64, testRecord, testRecord, testRecord, testRecord](legacy)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for method, err := range adapterCalls(adapted, ctx) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: error %v, want context.Canceled", method, err)
+		}
+	}
+	if legacy.calls != 0 {
+		t.Fatalf("legacy service called %d times with cancelled context", legacy.calls)
+	}
+	for method, err := range adapterCalls(adapted, context.Background()) {
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", method, err)
+		}
+	}
+	if legacy.calls != 7 {
+		t.Fatalf("legacy service called %d times, want 7", legacy.calls)
+	}
+}
+
+func TestAdaptBizServiceContextService(t *testing.T) {
+	service := newMemoryBizService()
+	if adapted := AdaptBizService[int64, testRecord, testRecord, testRecord, testRecord](service); adapted != BaseBizServiceContext[int64, testRecord, testRecord](service) {
+		t.Fatal("context service should be used directly")
+	}
+}
+
+func TestRequestContextPropagation(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1, UserID: 5})
+	fetch := func(*ginstarter.Request) Authority[int64] { return &testAuthority{id: 5} }
+	router := NewBaseRouterWithAuthority[int64, testRecord, testRecord, testRecord, testRecord](service, fetch, "UserID")
+	result := serve(t, router.QueryById(), testCall{
+		method: http.MethodGet,
+		path:   "by-id/1",
+		params: map[string]string{"id": "1"},
+		header: map[string]string{HeaderRequestID: "req-1"},
+	})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("query failed: %d %s", result.status, result.message)
+	}
+	ctx := service.lastCall(t, "QueryOne").ctx
+	authority, ok := AuthorityFromContext[int64](ctx)
+	if !ok || authority.GetIdentityID() != 5 {
+		t.Fatalf("authority not propagated: %v", authority)
+	}
+	metadata, ok := MetadataFromContext(ctx)
+	if !ok || metadata.Resource != "testRecord" || metadata.Operation != OperationQueryByID ||
+		metadata.RequestID != "req-1" || metadata.Path != "/test/by-id/1" {
+		t.Fatalf("unexpected metadata: %+v", metadata)
+	}
+
+	// 未设置认证方式时上下文不含认证信息
+	anonymous := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service)
+	serve(t, anonymous.QueryById(), testCall{method: http.MethodGet, params: map[string]string{"id": "1"}})
+	if _, ok = AuthorityFromContext[int64](service.lastCall(t, "QueryOne").ctx); ok {
+		t.Fatal("anonymous request should not carry authority")
+	}
+}
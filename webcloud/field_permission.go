@@ -0,0 +1,79 @@
+package webcloud
+
+import (
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// FieldAllowlist 字段安全设置 为空的设置项将沿用下一优先级的设置
+type FieldAllowlist struct {
+	SaveAllowedFields   []string // 允许自由保存的结构体字段名
+	ModifyAllowedFields []string // 允许自由更新的结构体字段名
+	QueryAllowedFields  []string // 允许自由查询的结构体字段名
+}
+
+// columnAllowlist 已转换为数据库字段的字段安全设置
+type columnAllowlist struct {
+	saveAllowedColumns   []string
+	modifyAllowedColumns []string
+	queryAllowedColumns  []string
+}
+
+func newColumnAllowlist(allowlist FieldAllowlist) columnAllowlist {
+	writeAllowed := func(fields []string) []string {
+		return coll.SliceFilter(structNames2Columns(fields), func(field string) bool {
+			return !coll.SliceContains(defaultForbitColumns, field)
+		})
+	}
+	return columnAllowlist{
+		saveAllowedColumns:   writeAllowed(allowlist.SaveAllowedFields),
+		modifyAllowedColumns: writeAllowed(allowlist.ModifyAllowedFields),
+		queryAllowedColumns:  structNames2Columns(allowlist.QueryAllowedFields),
+	}
+}
+
+func (c *columnAllowlist) columns(m Mode) []string {
+	switch m {
+	case ModeSave:
+		return c.saveAllowedColumns
+	case ModeModify:
+		return c.modifyAllowedColumns
+	case ModeQuery:
+		return c.queryAllowedColumns
+	}
+	return nil
+}
+
+// SetRoleAllowedFields 设置指定角色的字段安全设置 认证信息需实现 PermissionAuthority
+// 请求时按认证信息拥有的角色解析 拥有多个已设置的角色时取其并集 优先级高于平台规则与路由默认设置
+func (b *BaseRouter[ID, S, M, Q, D]) SetRoleAllowedFields(role string, allowlist FieldAllowlist) *BaseRouter[ID, S, M, Q, D] {
+	if b.roleAllowlists == nil {
+		b.roleAllowlists = make(map[string]columnAllowlist)
+	}
+	b.roleAllowlists[role] = newColumnAllowlist(allowlist)
+	return b
+}
+
+// roleAllowedColumns 获取当前请求认证信息所拥有角色允许自由操作的数据库字段 无适用角色时返回false
+func (b *BaseRouter[ID, S, M, Q, D]) roleAllowedColumns(request *ginstarter.Request, m Mode) ([]string, bool) {
+	if len(b.roleAllowlists) == 0 || b.authorityFetch == nil {
+		return nil, false
+	}
+	permissionAuthority, ok := AuthorityAs[PermissionAuthority](b.GetAuthorityData(request, true))
+	if !ok {
+		return nil, false
+	}
+	var result []string
+	matched := false
+	for _, role := range permissionAuthority.GetRoles() {
+		allowlist, ok := b.roleAllowlists[role]
+		if !ok {
+			continue
+		}
+		if columns := allowlist.columns(m); len(columns) > 0 {
+			result = coll.SliceUnion(result, columns)
+			matched = true
+		}
+	}
+	return result, matched
+}
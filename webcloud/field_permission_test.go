@@ -0,0 +1,71 @@
+package webcloud
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+func TestAllowedColumnsPriority(t *testing.T) {
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](nil).
+		SetRoleAllowedFields("editor", FieldAllowlist{ModifyAllowedFields: []string{"Name"}}).
+		SetRoleAllowedFields("owner", FieldAllowlist{ModifyAllowedFields: []string{"UserID"}, QueryAllowedFields: []string{"ID"}}).
+		SetRoleAllowedFields("auditor", FieldAllowlist{QueryAllowedFields: []string{"Name"}}).
+		SetPlatformRule("app", PlatformRule{ModifyAllowedFields: []string{"TenantID"}, QueryAllowedFields: []string{"UserID"}})
+	cases := []struct {
+		name     string
+		platform Platform
+		roles    []string
+		mode     Mode
+		columns  []string
+	}{
+		{"default", "web", nil, ModeModify, []string{"name", "tenant_id", "user_id"}},
+		{"platform over default", "app", nil, ModeModify, []string{"tenant_id"}},
+		{"role over platform", "app", []string{"editor"}, ModeModify, []string{"name"}},
+		{"roles union", "app", []string{"editor", "owner"}, ModeModify, []string{"name", "user_id"}},
+		{"unknown role ignored", "web", []string{"guest"}, ModeQuery, []string{"id", "name", "tenant_id", "user_id"}},
+		{"role without mode falls back to platform", "app", []string{"editor"}, ModeQuery, []string{"user_id"}},
+		{"query roles union", "app", []string{"owner", "auditor"}, ModeQuery, []string{"id", "name"}},
+		{"role without mode falls back to default", "web", []string{"editor"}, ModeSave, []string{"name", "tenant_id", "user_id"}},
+	}
+	for _, c := range cases {
+		router.authorityFetch = func(*ginstarter.Request) Authority[int64] {
+			return &platformAuthority{testPermissionAuthority: testPermissionAuthority{roles: c.roles}, platform: c.platform}
+		}
+		var columns []string
+		serve(t, func(request *ginstarter.Request) (ginstarter.Response, error) {
+			columns = slices.Clone(router.allowedColumns(request, c.mode))
+			return ginstarter.RespRestSuccess(), nil
+		}, testCall{})
+		slices.Sort(columns)
+		if !slices.Equal(columns, c.columns) {
+			t.Errorf("%s: columns %v, want %v", c.name, columns, c.columns)
+		}
+	}
+}
+
+// platformAuthority 指定平台的认证信息
+type platformAuthority struct {
+	testPermissionAuthority
+	platform Platform
+}
+
+func (a *platformAuthority) GetPlatform() Platform { return a.platform }
+
+func TestRoleAllowedFieldsOnModify(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1})
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service).
+		SetRoleAllowedFields("editor", FieldAllowlist{ModifyAllowedFields: []string{"Name"}})
+	router.authorityFetch = func(*ginstarter.Request) Authority[int64] {
+		return &testPermissionAuthority{testAuthority: testAuthority{id: 1}, roles: []string{"editor"}}
+	}
+	call := testCall{params: map[string]string{"id": "1"}, body: `{"userId":2}`}
+	if result := serve(t, router.ModifyById(), call); result.status != ginstarter.StatusCodeBadRequestParameters {
+		t.Fatalf("field outside role allowlist accepted: %d", result.status)
+	}
+	call.body = `{"name":"b"}`
+	if result := serve(t, router.ModifyById(), call); result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("field in role allowlist rejected: %d %s", result.status, result.message)
+	}
+}
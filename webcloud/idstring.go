@@ -0,0 +1,235 @@
+package webcloud
+
+import (
+	"encoding"
+	sdkjson "encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// EnableStringIDs 响应中的64位整数主键以字符串输出 避免JavaScript客户端精度丢失 请求中同时接受字符串与数值
+// 作用于响应结构体D 分页结果与修订版本中字段名以ID/Id(IDs/Ids)结尾的64位整数字段(含切片) 以及Save返回的主键
+// 嵌套结构体及结构体切片中的字段同样生效
+// 其他字段可通过标签 `idstring:"true"` 声明 `idstring:"-"` 排除
+func (b *BaseRouter[ID, S, M, Q, D]) EnableStringIDs() *BaseRouter[ID, S, M, Q, D] {
+	b.stringIDs = true
+	return b
+}
+
+var idFieldCache sync.Map
+
+var (
+	jsonMarshalerType = reflect.TypeFor[sdkjson.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// parseIDFields 解析结构体中需以字符串输出的64位整数字段的json路径
+// 包含匿名嵌入结构体 嵌套结构体及结构体切片中的字段 路径途经切片时作用于每个元素
+func parseIDFields(t reflect.Type) [][]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if v, ok := idFieldCache.Load(t); ok {
+		return v.([][]string)
+	}
+	fields := collectIDFields(t, make(map[reflect.Type]bool))
+	idFieldCache.Store(t, fields)
+	return fields
+}
+
+// collectIDFields 递归解析结构体中的字段路径 visiting用于跳过自引用的类型
+func collectIDFields(t reflect.Type, visiting map[reflect.Type]bool) [][]string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	var fields [][]string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" {
+			fields = append(fields, collectIDFields(field.Type, visiting)...)
+			continue
+		}
+		tag := field.Tag.Get("idstring")
+		if tag == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		if isInt64Type(field.Type) {
+			if tag == "true" || isIDFieldName(field.Name) {
+				fields = append(fields, []string{jsonName})
+			}
+			continue
+		}
+		if nested := nestedStructType(field.Type); nested != nil {
+			for _, path := range collectIDFields(nested, visiting) {
+				fields = append(fields, append([]string{jsonName}, path...))
+			}
+		}
+	}
+	return fields
+}
+
+// nestedStructType 获取字段中以json对象输出的结构体类型 含指针与切片 自定义json编码的类型除外
+func nestedStructType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	pointer := reflect.PointerTo(t)
+	if pointer.Implements(jsonMarshalerType) || pointer.Implements(textMarshalerType) {
+		return nil
+	}
+	return t
+}
+
+// convertIDPath 转换json对象中路径对应的值 途经切片时逐个转换 返回是否有值被转换
+func convertIDPath(value any, path []string, convert func(any) (any, bool)) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		item, ok := v[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			var converted bool
+			v[path[0]], converted = convert(item)
+			return converted
+		}
+		return convertIDPath(item, path[1:], convert)
+	case []any:
+		changed := false
+		for _, item := range v {
+			if convertIDPath(item, path, convert) {
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+func isIDFieldName(name string) bool {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "s"), "S")
+	return strings.HasSuffix(name, "ID") || strings.HasSuffix(name, "Id")
+}
+
+// isInt64Type 是否为64位整数类型或其指针 切片
+func isInt64Type(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint:
+		return strconv.IntSize == 64
+	case reflect.Int64, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// activeIDFields 获取响应结构体D中需以字符串输出的字段
+func (b *BaseRouter[ID, S, M, Q, D]) activeIDFields() [][]string {
+	if !b.stringIDs {
+		return nil
+	}
+	return parseIDFields(reflect.TypeFor[D]())
+}
+
+// stringifyNumbers 将json数值转换为字符串 切片逐个转换
+func stringifyNumbers(value any) (any, bool) {
+	switch v := value.(type) {
+	case sdkjson.Number:
+		return v.String(), true
+	case []any:
+		changed := false
+		for i, item := range v {
+			var converted bool
+			v[i], converted = stringifyNumbers(item)
+			changed = changed || converted
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// renderID 转换Save响应的主键
+func (b *BaseRouter[ID, S, M, Q, D]) renderID(id ID) any {
+	if b.stringIDs && isInt64Type(reflect.TypeFor[ID]()) {
+		return fmt.Sprint(id)
+	}
+	return id
+}
+
+// acceptStringIDs 将Save请求体中以字符串传递的64位整数字段转换为数值 以便绑定至结构体S
+func (b *BaseRouter[ID, S, M, Q, D]) acceptStringIDs(request *ginstarter.Request) {
+	if !b.stringIDs || !strings.HasPrefix(request.RawGinContext().ContentType(), gin.MIMEJSON) {
+		return
+	}
+	fields := parseIDFields(reflect.TypeFor[S]())
+	if len(fields) == 0 {
+		return
+	}
+	body, err := b.readBody(request)
+	if err != nil {
+		return
+	}
+	// 请求体有误时交由绑定流程响应错误
+	object, err := decodeJsonObject(body, b.bodyLimits().MaxDepth)
+	if err != nil {
+		return
+	}
+	changed := false
+	for _, path := range fields {
+		if convertIDPath(object, path, numericStrings) {
+			changed = true
+		}
+	}
+	if changed {
+		setRawBody(request, json.ToBytes(object))
+	}
+}
+
+// numericStrings 将数值字符串转换为json数值 切片逐个转换
+func numericStrings(value any) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return sdkjson.Number(v), true
+		}
+		if _, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return sdkjson.Number(v), true
+		}
+	case []any:
+		changed := false
+		for i, item := range v {
+			var converted bool
+			v[i], converted = numericStrings(item)
+			changed = changed || converted
+		}
+		return v, changed
+	}
+	return value, false
+}
@@ -0,0 +1,125 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testIDItem struct {
+	SkuID int64  `json:"skuId"`
+	Count int64  `json:"count"`
+	Note  string `json:"note"`
+}
+
+type testIDAddress struct {
+	RegionID int64 `json:"regionId"`
+	Code     int64 `json:"code" idstring:"true"`
+}
+
+type testIDNode struct {
+	NodeID   int64         `json:"nodeId"`
+	Children []*testIDNode `json:"children"`
+}
+
+type testIDOrder struct {
+	ID       int64          `json:"id"`
+	Items    []testIDItem   `json:"items"`
+	Address  *testIDAddress `json:"address"`
+	Hidden   testIDAddress  `json:"hidden" idstring:"-"`
+	Tree     testIDNode     `json:"tree"`
+	Created  time.Time      `json:"created"`
+	OwnerIDs []int64        `json:"ownerIds"`
+}
+
+func TestParseIDFieldsNested(t *testing.T) {
+	fields := parseIDFields(reflect.TypeFor[testIDOrder]())
+	expected := [][]string{
+		{"id"},
+		{"items", "skuId"},
+		{"address", "regionId"},
+		{"address", "code"},
+		{"tree", "nodeId"},
+		{"ownerIds"},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("unexpected id fields: %v", fields)
+	}
+}
+
+func TestConvertRecordNestedIDs(t *testing.T) {
+	order := &testIDOrder{
+		ID:       9007199254740993,
+		Items:    []testIDItem{{SkuID: 1, Count: 2}, {SkuID: 3, Count: 4}},
+		Address:  &testIDAddress{RegionID: 5, Code: 6},
+		Hidden:   testIDAddress{RegionID: 7},
+		OwnerIDs: []int64{8, 9},
+	}
+	result := convertRecord(order, nil, parseIDFields(reflect.TypeFor[testIDOrder]())).(map[string]any)
+	if result["id"] != "9007199254740993" {
+		t.Fatalf("top level id not converted: %#v", result["id"])
+	}
+	items := result["items"].([]any)
+	for i, sku := range []string{"1", "3"} {
+		item := items[i].(map[string]any)
+		if item["skuId"] != sku || item["count"] != sdkjson.Number([]string{"2", "4"}[i]) {
+			t.Fatalf("item %d converted incorrectly: %#v", i, item)
+		}
+	}
+	address := result["address"].(map[string]any)
+	if address["regionId"] != "5" || address["code"] != "6" {
+		t.Fatalf("nested struct not converted: %#v", address)
+	}
+	if hidden := result["hidden"].(map[string]any); hidden["regionId"] != sdkjson.Number("7") {
+		t.Fatalf("excluded struct converted: %#v", hidden)
+	}
+	if !reflect.DeepEqual(result["ownerIds"], []any{"8", "9"}) {
+		t.Fatalf("id slice not converted: %#v", result["ownerIds"])
+	}
+}
+
+func TestConvertRecordNilNested(t *testing.T) {
+	result := convertRecord(&testIDOrder{ID: 1}, nil, parseIDFields(reflect.TypeFor[testIDOrder]())).(map[string]any)
+	if result["address"] != nil || result["items"] != nil {
+		t.Fatalf("nil nested values changed: %#v", result)
+	}
+}
+
+func TestAcceptNestedStringIDs(t *testing.T) {
+	object := map[string]any{
+		"items":   []any{map[string]any{"skuId": "9223372036854775807", "note": "12"}},
+		"address": map[string]any{"regionId": "x"},
+	}
+	changed := false
+	for _, path := range parseIDFields(reflect.TypeFor[testIDOrder]()) {
+		if convertIDPath(object, path, numericStrings) {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Fatal("nested string id not converted")
+	}
+	item := object["items"].([]any)[0].(map[string]any)
+	if item["skuId"] != sdkjson.Number("9223372036854775807") || item["note"] != "12" {
+		t.Fatalf("unexpected item: %#v", item)
+	}
+	if object["address"].(map[string]any)["regionId"] != "x" {
+		t.Fatal("non numeric string should be kept")
+	}
+}
+
+func TestMaskRevisionConvertsIDs(t *testing.T) {
+	snapshot := map[string]any{"id": int64(10), "items": []any{map[string]any{"skuId": int64(11)}}}
+	revision := &Revision{Snapshot: snapshot}
+	converted := maskRevision(revision, nil, parseIDFields(reflect.TypeFor[testIDOrder]()))
+	if converted.Snapshot["id"] != "10" {
+		t.Fatalf("revision id not converted: %#v", converted.Snapshot["id"])
+	}
+	if item := converted.Snapshot["items"].([]any)[0].(map[string]any); item["skuId"] != "11" {
+		t.Fatalf("revision nested id not converted: %#v", item)
+	}
+	if snapshot["id"] != int64(10) || snapshot["items"].([]any)[0].(map[string]any)["skuId"] != int64(11) {
+		t.Fatal("stored snapshot modified")
+	}
+}
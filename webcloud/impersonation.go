@@ -0,0 +1,181 @@
+package webcloud
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	AuditActionImpersonate = "impersonate" // 代理其他身份
+
+	defaultActAsHeader = "X-Act-As"
+)
+
+var (
+	ErrImpersonationForbidden = errors.New("impersonation forbidden")
+	ErrImpersonationTenant    = errors.New("impersonation target belongs to another tenant")
+)
+
+// ImpersonatedAuthority 代理身份的认证信息 数据权限按被代理身份(有效身份)控制
+type ImpersonatedAuthority[ID IDType] struct {
+	Authority[ID]               // 有效身份
+	Real          Authority[ID] // 实际发起请求的身份
+}
+
+// Unwrap 获取有效身份的认证信息
+func (i *ImpersonatedAuthority[ID]) Unwrap() Authority[ID] {
+	return i.Authority
+}
+
+// GetRealAuthority 获取实际发起请求的身份
+func (i *ImpersonatedAuthority[ID]) GetRealAuthority() Authority[ID] {
+	return i.Real
+}
+
+// ImpersonationConfig 身份代理配置
+type ImpersonationConfig[ID IDType] struct {
+	HeaderName string // 携带被代理身份标识的请求头 默认 X-Act-As
+	Permission string // 允许代理其他身份所需的权限 必须设置
+	// Resolve 获取被代理身份的认证信息 返回nil表示不允许代理该身份
+	// 为nil时被代理身份沿用实际身份的平台与租户 且不含角色等扩展信息
+	// 实际身份属于某一租户时 被代理身份必须属于同一租户 否则拒绝代理 未属于任何租户的实际身份可代理任意租户的身份
+	Resolve func(request *ginstarter.Request, realAuthority Authority[ID], targetID ID) Authority[ID]
+}
+
+// actAsAuthority 未提供 Resolve 时的被代理身份
+type actAsAuthority[ID IDType] struct {
+	identityID ID
+	platform   Platform
+	tenantID   any
+}
+
+func (a *actAsAuthority[ID]) GetIdentityID() ID {
+	return a.identityID
+}
+
+func (a *actAsAuthority[ID]) GetPlatform() Platform {
+	return a.platform
+}
+
+// GetTenantID 沿用实际身份的租户
+func (a *actAsAuthority[ID]) GetTenantID() any {
+	return a.tenantID
+}
+
+// impersonationFetch 为AuthorityFetch启用身份代理 record 记录每次代理的审计事件 拒绝代理时err不为空
+func impersonationFetch[ID IDType](authorityFetch AuthorityFetch[ID], config ImpersonationConfig[ID],
+	record func(request *ginstarter.Request, authority Authority[ID], err error)) AuthorityFetch[ID] {
+	if config.Permission == "" {
+		panic(errors.New("impersonation requires permission"))
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = defaultActAsHeader
+	}
+	return func(request *ginstarter.Request) Authority[ID] {
+		realAuthority := authorityFetch(request)
+		if realAuthority == nil {
+			return nil
+		}
+		target := request.GetHeader(config.HeaderName)
+		if target == "" {
+			return realAuthority
+		}
+		reject := func(statusCode ginstarter.StatusCode, err error) {
+			logger.Logrus().Warningln("impersonation rejected, target:", target, "identity:", realAuthority.GetIdentityID(), err)
+			record(request, realAuthority, err)
+			AbortRequest(request, int(statusCode), err)
+		}
+		if !HasPermission(realAuthority, config.Permission) {
+			reject(ginstarter.StatusCodeForbidden, ErrImpersonationForbidden)
+		}
+		targetID, err := CovertStringToID[ID](target)
+		if err != nil {
+			reject(ginstarter.StatusCodeBadRequestParameters, err)
+		}
+		realTenantID, hasTenant := GetTenantID(realAuthority)
+		var effective Authority[ID]
+		if config.Resolve != nil {
+			effective = config.Resolve(request, realAuthority, targetID)
+		} else {
+			effective = &actAsAuthority[ID]{identityID: targetID, platform: realAuthority.GetPlatform(), tenantID: realTenantID}
+		}
+		if effective == nil {
+			reject(ginstarter.StatusCodeForbidden, ErrImpersonationForbidden)
+		}
+		if hasTenant {
+			if tenantID, ok := GetTenantID(effective); !ok || fmt.Sprint(tenantID) != fmt.Sprint(realTenantID) {
+				reject(ginstarter.StatusCodeForbidden, ErrImpersonationTenant)
+			}
+		}
+		// 被代理身份沿用实际身份的认证方式 使接受的认证方式限制对代理请求同样生效
+		if scheme, ok := GetAuthScheme(realAuthority); ok {
+			if _, ok = GetAuthScheme(effective); !ok {
+				effective = &SchemeAuthority[ID]{Authority: effective, Scheme: scheme}
+			}
+		}
+		authority := &ImpersonatedAuthority[ID]{Authority: effective, Real: realAuthority}
+		record(request, authority, nil)
+		return authority
+	}
+}
+
+// SetImpersonation 启用身份代理 拥有代理权限的认证信息可通过请求头以其他身份访问数据
+// GetAuthorityData 返回 *ImpersonatedAuthority 有效身份与实际身份均可获取 每次代理及被拒绝的代理均会记录审计事件
+func (b *BaseRouter[ID, S, M, Q, D]) SetImpersonation(config ImpersonationConfig[ID]) *BaseRouter[ID, S, M, Q, D] {
+	if b.authorityFetch == nil {
+		panic(errors.New("impersonation requires authority fetch"))
+	}
+	b.authorityFetch = impersonationFetch(b.authorityFetch, config, func(request *ginstarter.Request, authority Authority[ID], err error) {
+		b.audit(impersonationEvent(b.newAuditEvent(request, AuditActionImpersonate, authority), request, config, err))
+	})
+	return b
+}
+
+// SetImpersonation 启用身份代理 与 BaseRouter.SetImpersonation 一致 sink为空时审计事件输出至日志
+func (s *SimpleRouter[ID]) SetImpersonation(config ImpersonationConfig[ID], sink AuditSink) *SimpleRouter[ID] {
+	if s.authorityFetch == nil {
+		panic(errors.New("impersonation requires authority fetch"))
+	}
+	if sink == nil {
+		sink = logAuditSink{}
+	}
+	s.authorityFetch = impersonationFetch(s.authorityFetch, config, func(request *ginstarter.Request, authority Authority[ID], err error) {
+		sink.Record(impersonationEvent(newAuditEvent(request, "", AuditActionImpersonate, authority), request, config, err))
+	})
+	return s
+}
+
+// impersonationEvent 补充代理审计事件的代理目标与结果
+func impersonationEvent[ID IDType](event *AuditEvent, request *ginstarter.Request, config ImpersonationConfig[ID], err error) *AuditEvent {
+	headerName := config.HeaderName
+	if headerName == "" {
+		headerName = defaultActAsHeader
+	}
+	event.ResourceID = request.GetHeader(headerName)
+	event.Result = AuditResultSuccess
+	if err != nil {
+		event.Result = AuditResultFailure
+		event.Error = err.Error()
+	}
+	return event
+}
+
+// GetRealAuthorityData 获取实际发起请求的身份 未代理其他身份时与 GetAuthorityData 一致
+func (b *BaseRouter[ID, S, M, Q, D]) GetRealAuthorityData(request *ginstarter.Request, notRequired ...bool) Authority[ID] {
+	return realAuthority(b.GetAuthorityData(request, notRequired...))
+}
+
+// GetRealAuthorityData 获取实际发起请求的身份 未代理其他身份时与 GetAuthorityData 一致
+func (s *SimpleRouter[ID]) GetRealAuthorityData(request *ginstarter.Request, notRequired ...bool) Authority[ID] {
+	return realAuthority(s.GetAuthorityData(request, notRequired...))
+}
+
+func realAuthority[ID IDType](authority Authority[ID]) Authority[ID] {
+	if impersonated, ok := authority.(*ImpersonatedAuthority[ID]); ok {
+		return impersonated.Real
+	}
+	return authority
+}
@@ -0,0 +1,117 @@
+package webcloud
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const testImpersonatePermission = "user:impersonate"
+
+// impersonationRouter 以租户1中 bearer 认证的身份1访问 permitted 控制其是否拥有代理权限
+func impersonationRouter(permitted bool, config ImpersonationConfig[int64]) (*testRouter, *MemoryAuditSink, *int) {
+	real := &testPermissionAuthority{testAuthority: testAuthority{id: 1, tenantID: int64(1)}}
+	if permitted {
+		real.permissions = []string{testImpersonatePermission}
+	}
+	calls := 0
+	fetch := func(request *ginstarter.Request) Authority[int64] {
+		calls++
+		return &SchemeAuthority[int64]{Authority: real, Scheme: "bearer"}
+	}
+	sink := NewMemoryAuditSink(10)
+	config.Permission = testImpersonatePermission
+	router := NewBaseRouterWithAuthority[int64, testRecord, testRecord, testRecord, testRecord](nil, fetch, "UserID").
+		SetTenantLimit("TenantID").
+		AcceptAuthSchemes([]AuthScheme{"bearer"}, OperationQueryByID).
+		SetAuditSink(sink).
+		SetImpersonation(config)
+	return router, sink, &calls
+}
+
+func TestImpersonationRejected(t *testing.T) {
+	cases := []struct {
+		name      string
+		permitted bool
+		target    string
+		resolve   func(*ginstarter.Request, Authority[int64], int64) Authority[int64]
+		status    ginstarter.StatusCode
+	}{
+		{"permission denied", false, "2", nil, ginstarter.StatusCodeForbidden},
+		{"bad id", true, "abc", nil, ginstarter.StatusCodeBadRequestParameters},
+		{"resolve denied", true, "2", func(*ginstarter.Request, Authority[int64], int64) Authority[int64] { return nil }, ginstarter.StatusCodeForbidden},
+		{"other tenant", true, "2", func(_ *ginstarter.Request, _ Authority[int64], id int64) Authority[int64] {
+			return &testAuthority{id: id, tenantID: int64(2)}
+		}, ginstarter.StatusCodeForbidden},
+	}
+	for _, c := range cases {
+		router, sink, calls := impersonationRouter(c.permitted, ImpersonationConfig[int64]{Resolve: c.resolve})
+		handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+			t.Errorf("%s: handler executed", c.name)
+			return ginstarter.RespRestSuccess(), nil
+		})
+		result := serve(t, handler, testCall{method: http.MethodGet, header: map[string]string{defaultActAsHeader: c.target}})
+		if result.status != c.status {
+			t.Errorf("%s: status %d, want %d", c.name, result.status, c.status)
+		}
+		if *calls != 1 {
+			t.Errorf("%s: authority fetch executed %d times", c.name, *calls)
+		}
+		events := sink.Events()
+		if len(events) != 1 || events[0].Result != AuditResultFailure || events[0].IdentityID != int64(1) || events[0].ResourceID != c.target {
+			t.Errorf("%s: unexpected audit events: %+v", c.name, events)
+		}
+	}
+}
+
+func TestImpersonationInheritsTenantAndScheme(t *testing.T) {
+	router, sink, calls := impersonationRouter(true, ImpersonationConfig[int64]{})
+	handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		param := map[string]any{}
+		if !router.SetAuthorityLimitMap(request, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		if real := router.GetRealAuthorityData(request); real.GetIdentityID() != 1 {
+			t.Errorf("unexpected real identity: %v", real.GetIdentityID())
+		}
+		return ginstarter.RespRestSuccess(param), nil
+	})
+	result := serve(t, handler, testCall{method: http.MethodGet, header: map[string]string{defaultActAsHeader: "2"}})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("impersonated request rejected: %d %s", result.status, result.message)
+	}
+	var param map[string]any
+	result.decodeData(t, &param)
+	if fmt.Sprint(param["user_id"]) != "2" || fmt.Sprint(param["tenant_id"]) != "1" {
+		t.Fatalf("unexpected limit conditions: %v", param)
+	}
+	if *calls != 1 {
+		t.Fatalf("authority fetch executed %d times", *calls)
+	}
+	events := sink.Events()
+	if len(events) != 1 || events[0].Action != AuditActionImpersonate || events[0].Result != AuditResultSuccess ||
+		events[0].IdentityID != int64(2) || events[0].RealID != int64(1) {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+}
+
+func TestSimpleRouterImpersonation(t *testing.T) {
+	real := &testPermissionAuthority{testAuthority: testAuthority{id: 1}, permissions: []string{testImpersonatePermission}}
+	sink := NewMemoryAuditSink(10)
+	router := NewSimpleRouter[int64](func(*ginstarter.Request) Authority[int64] { return real }).
+		SetImpersonation(ImpersonationConfig[int64]{Permission: testImpersonatePermission}, sink)
+	handler := func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return ginstarter.RespRestSuccess([]int64{router.GetAuthorityData(request).GetIdentityID(), router.GetRealAuthorityData(request).GetIdentityID()}), nil
+	}
+	result := serve(t, handler, testCall{method: http.MethodGet, header: map[string]string{defaultActAsHeader: "3"}})
+	var ids []int64
+	result.decodeData(t, &ids)
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 1 {
+		t.Fatalf("unexpected identities: %v", ids)
+	}
+	if events := sink.Events(); len(events) != 1 || events[0].RealID != int64(1) {
+		t.Fatalf("unexpected audit events: %+v", events)
+	}
+}
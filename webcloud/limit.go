@@ -0,0 +1,115 @@
+package webcloud
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const headerRetryAfter = "Retry-After"
+
+// OperationLimit 基础操作的超时与并发限制
+type OperationLimit struct {
+	Timeout     time.Duration // 超时时间 超时后取消传递至业务服务的上下文 业务服务因超时失败时响应504 为0时不限制
+	MaxInFlight int           // 最大并发请求数 超出时直接响应503 为0时不限制
+	RetryAfter  time.Duration // 响应503/504时建议的重试间隔 默认1秒
+}
+
+// LimitStats 基础操作的限制计数
+type LimitStats struct {
+	InFlight int64 `json:"inFlight"` // 执行中的请求数
+	Rejected int64 `json:"rejected"` // 超出并发限制被拒绝的请求数
+	TimedOut int64 `json:"timedOut"` // 超时的请求数
+}
+
+type operationLimiter struct {
+	OperationLimit
+	slots    chan struct{}
+	inFlight atomic.Int64
+	rejected atomic.Int64
+	timedOut atomic.Int64
+}
+
+// SetOperationLimit 设置基础操作的超时与并发限制 未指定操作时作用于全部基础操作
+// 超时仅能取消实现了 BaseBizServiceContext 的业务服务 超时后仍成功返回的结果正常响应
+func (b *BaseRouter[ID, S, M, Q, D]) SetOperationLimit(limit OperationLimit, operations ...Operation) *BaseRouter[ID, S, M, Q, D] {
+	if len(operations) == 0 {
+		operations = AllOperations
+	}
+	if limit.RetryAfter <= 0 {
+		limit.RetryAfter = time.Second
+	}
+	if b.operationLimits == nil {
+		b.operationLimits = make(map[Operation]*operationLimiter)
+	}
+	for _, operation := range operations {
+		limiter := &operationLimiter{OperationLimit: limit}
+		if limit.MaxInFlight > 0 {
+			limiter.slots = make(chan struct{}, limit.MaxInFlight)
+		}
+		b.operationLimits[operation] = limiter
+	}
+	return b
+}
+
+// LimitStats 获取设置了限制的基础操作的计数
+func (b *BaseRouter[ID, S, M, Q, D]) LimitStats() map[Operation]LimitStats {
+	stats := make(map[Operation]LimitStats, len(b.operationLimits))
+	for operation, limiter := range b.operationLimits {
+		stats[operation] = LimitStats{
+			InFlight: limiter.inFlight.Load(),
+			Rejected: limiter.rejected.Load(),
+			TimedOut: limiter.timedOut.Load(),
+		}
+	}
+	return stats
+}
+
+// retryAfterResponse 响应指定状态并设置重试间隔
+func retryAfterResponse(statusCode ginstarter.StatusCode, retryAfter time.Duration) ginstarter.Response {
+	response := ginstarter.RespRestStatusError(statusCode)
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	response.Data().AddHeader(headerRetryAfter, strconv.FormatInt(seconds, 10))
+	return response
+}
+
+// limit 在超时与并发限制下执行基础操作
+func (b *BaseRouter[ID, S, M, Q, D]) limit(request *ginstarter.Request, operation Operation, handler ginstarter.HandlerWrapper) (ginstarter.Response, error) {
+	limiter := b.operationLimits[operation]
+	if limiter == nil {
+		return handler(request)
+	}
+	if limiter.slots != nil {
+		select {
+		case limiter.slots <- struct{}{}:
+			defer func() { <-limiter.slots }()
+		default:
+			limiter.rejected.Add(1)
+			return retryAfterResponse(ginstarter.StatusCodeServiceUnavailable, limiter.RetryAfter), nil
+		}
+	}
+	limiter.inFlight.Add(1)
+	defer limiter.inFlight.Add(-1)
+	if limiter.Timeout <= 0 {
+		return handler(request)
+	}
+	rawRequest := request.RawGinContext().Request
+	ctx, cancel := context.WithTimeout(rawRequest.Context(), limiter.Timeout)
+	defer cancel()
+	request.RawGinContext().Request = rawRequest.WithContext(ctx)
+	response, err := handler(request)
+	if deadlineExceeded(ctx, err) {
+		limiter.timedOut.Add(1)
+		return retryAfterResponse(ginstarter.StatusCodeTimeout, limiter.RetryAfter), nil
+	}
+	return response, err
+}
+
+// deadlineExceeded 基础操作是否因超时失败 超时后成功返回或因其他原因失败时返回false
+func deadlineExceeded(ctx context.Context, err error) bool {
+	return err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && errors.Is(err, context.DeadlineExceeded)
+}
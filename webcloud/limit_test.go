@@ -0,0 +1,32 @@
+package webcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeadlineExceeded(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	active := context.Background()
+	cases := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"succeeded after deadline", expired, nil, false},
+		{"failed with deadline", expired, fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+		{"failed otherwise after deadline", expired, errors.New("duplicate key"), false},
+		{"failed before deadline", active, context.DeadlineExceeded, false},
+	}
+	for _, c := range cases {
+		if got := deadlineExceeded(c.ctx, c.err); got != c.want {
+			t.Errorf("%s: deadlineExceeded = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
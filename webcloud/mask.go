@@ -0,0 +1,250 @@
+package webcloud
+
+import (
+	"bytes"
+	sdkjson "encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// MaskKind 脱敏方式
+type MaskKind string
+
+const (
+	MaskPhone   MaskKind = "phone"   // 手机号 保留前3位与后4位
+	MaskEmail   MaskKind = "email"   // 邮箱 保留用户名首字符与域名
+	MaskIDCard  MaskKind = "idcard"  // 证件号 保留前4位与后4位
+	MaskName    MaskKind = "name"    // 姓名 保留首字符
+	MaskPartial MaskKind = "partial" // 通用 保留首尾字符
+	MaskDrop    MaskKind = "drop"    // 不响应该字段
+)
+
+// maskField 响应结构体中需要脱敏的字段
+type maskField struct {
+	jsonName   string
+	kind       MaskKind
+	permission string // 拥有该权限时不脱敏
+}
+
+// SetMaskPermission 设置查看敏感字段原文所需的默认权限 认证信息需实现 PermissionAuthority
+// 响应结构体D中通过标签声明需要脱敏的字段 例: `mask:"phone"` `mask:"email,perm=user:pii"`
+// 字段标签中的perm优先于默认权限 均未设置时总是脱敏
+func (b *BaseRouter[ID, S, M, Q, D]) SetMaskPermission(permission string) *BaseRouter[ID, S, M, Q, D] {
+	b.maskPermission = permission
+	return b
+}
+
+var maskFieldCache sync.Map
+
+// parseMaskFields 解析结构体中声明了脱敏标签的字段 包含匿名嵌入结构体的字段
+func parseMaskFields(t reflect.Type) []maskField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if v, ok := maskFieldCache.Load(t); ok {
+		return v.([]maskField)
+	}
+	var fields []maskField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" {
+			fields = append(fields, parseMaskFields(field.Type)...)
+			continue
+		}
+		tag, ok := field.Tag.Lookup("mask")
+		if !ok || tag == "" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+		options := strings.Split(tag, ",")
+		masked := maskField{jsonName: jsonName, kind: MaskKind(strings.TrimSpace(options[0]))}
+		for _, option := range options[1:] {
+			if permission, ok := strings.CutPrefix(strings.TrimSpace(option), "perm="); ok {
+				masked.permission = permission
+			}
+		}
+		fields = append(fields, masked)
+	}
+	maskFieldCache.Store(t, fields)
+	return fields
+}
+
+// activeMaskFields 获取当前请求需要脱敏的字段
+func (b *BaseRouter[ID, S, M, Q, D]) activeMaskFields(request *ginstarter.Request) []maskField {
+	fields := parseMaskFields(reflect.TypeFor[D]())
+	if len(fields) == 0 {
+		return nil
+	}
+	var authority Authority[ID]
+	if b.authorityFetch != nil {
+		authority = b.GetAuthorityData(request, true)
+	}
+	var active []maskField
+	for _, field := range fields {
+		permission := field.permission
+		if permission == "" {
+			permission = b.maskPermission
+		}
+		if permission != "" && authority != nil && HasPermission(authority, permission) {
+			continue
+		}
+		active = append(active, field)
+	}
+	return active
+}
+
+// renderRecord 转换单条响应数据
+func (b *BaseRouter[ID, S, M, Q, D]) renderRecord(request *ginstarter.Request, record *D) any {
+	fields, idFields := b.activeMaskFields(request), b.activeIDFields()
+	if len(fields) == 0 && len(idFields) == 0 {
+		return record
+	}
+	return convertRecord(record, fields, idFields)
+}
+
+// renderRecords 转换多条响应数据
+func (b *BaseRouter[ID, S, M, Q, D]) renderRecords(request *ginstarter.Request, records []*D) any {
+	fields, idFields := b.activeMaskFields(request), b.activeIDFields()
+	if len(fields) == 0 && len(idFields) == 0 {
+		return records
+	}
+	result := make([]any, len(records))
+	for i, record := range records {
+		result[i] = convertRecord(record, fields, idFields)
+	}
+	return result
+}
+
+// renderPager 转换分页响应数据
+func (b *BaseRouter[ID, S, M, Q, D]) renderPager(request *ginstarter.Request, pager Pager[D]) any {
+	fields, idFields := b.activeMaskFields(request), b.activeIDFields()
+	if len(fields) == 0 && len(idFields) == 0 {
+		return pager
+	}
+	records := make([]any, len(pager.Records))
+	for i, record := range pager.Records {
+		records[i] = convertRecord(record, fields, idFields)
+	}
+	return renderedPager{
+		Records: records,
+		Total:   pager.Total,
+		Size:    pager.Size,
+		Number:  pager.Number,
+	}
+}
+
+// renderedPager 转换后的分页响应信息 与 Pager 的json结构一致
+type renderedPager struct {
+	Records []any `json:"records"`
+	Total   int64 `json:"total"`
+	Size    int   `json:"size"`
+	Number  int   `json:"number"`
+}
+
+// toJsonMap 将结构体转换为以json key为key的map 数值保持原始精度
+func toJsonMap(value any) (map[string]any, error) {
+	data, err := json.ToBytesError(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := sdkjson.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result map[string]any
+	if err = decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// convertRecord 对单条数据脱敏 并将idFields路径中的数值转换为字符串
+func convertRecord(record any, fields []maskField, idFields [][]string) any {
+	if record == nil {
+		return nil
+	}
+	result, err := toJsonMap(record)
+	if err != nil {
+		// 无法转换时不响应原始数据
+		logger.Logrus().Errorln("mask record error:", err)
+		return nil
+	}
+	if result == nil {
+		return nil
+	}
+	for _, field := range fields {
+		value, ok := result[field.jsonName]
+		if !ok {
+			continue
+		}
+		if field.kind == MaskDrop {
+			delete(result, field.jsonName)
+			continue
+		}
+		if text, ok := value.(string); ok {
+			result[field.jsonName] = MaskString(text, field.kind)
+		}
+	}
+	for _, path := range idFields {
+		convertIDPath(result, path, stringifyNumbers)
+	}
+	return result
+}
+
+// MaskString 按脱敏方式处理字符串
+func MaskString(value string, kind MaskKind) string {
+	runes := []rune(value)
+	length := len(runes)
+	if length == 0 {
+		return value
+	}
+	keep := func(prefix, suffix int) string {
+		if prefix+suffix >= length {
+			return strings.Repeat("*", length)
+		}
+		return string(runes[:prefix]) + strings.Repeat("*", length-prefix-suffix) + string(runes[length-suffix:])
+	}
+	switch kind {
+	case MaskPhone:
+		return keep(3, 4)
+	case MaskIDCard:
+		return keep(4, 4)
+	case MaskName:
+		if length == 1 {
+			return "*"
+		}
+		return keep(1, 0)
+	case MaskEmail:
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok {
+			return MaskString(value, MaskPartial)
+		}
+		localRunes := []rune(local)
+		if len(localRunes) == 0 {
+			return "***@" + domain
+		}
+		return string(localRunes[0]) + "***@" + domain
+	case MaskDrop:
+		return ""
+	default:
+		if length <= 2 {
+			return strings.Repeat("*", length)
+		}
+		return keep(1, 1)
+	}
+}
@@ -0,0 +1,96 @@
+package webcloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+type testMaskedRecord struct {
+	ID    int64  `json:"id"`
+	Phone string `json:"phone" mask:"phone"`
+	Email string `json:"email" mask:"email,perm=user:email"`
+	Note  string `json:"note" mask:"drop"`
+}
+
+func TestMaskString(t *testing.T) {
+	cases := []struct {
+		value    string
+		kind     MaskKind
+		expected string
+	}{
+		{"13812345678", MaskPhone, "138****5678"},
+		{"1234567", MaskPhone, "*******"},
+		{"110101199001011234", MaskIDCard, "1101**********1234"},
+		{"张三丰", MaskName, "张**"},
+		{"张", MaskName, "*"},
+		{"alice@example.com", MaskEmail, "a***@example.com"},
+		{"@example.com", MaskEmail, "***@example.com"},
+		{"not-an-email", MaskEmail, "n**********l"},
+		{"secret", MaskPartial, "s****t"},
+		{"ab", MaskPartial, "**"},
+		{"anything", MaskDrop, ""},
+		{"", MaskPhone, ""},
+	}
+	for _, c := range cases {
+		if actual := MaskString(c.value, c.kind); actual != c.expected {
+			t.Errorf("MaskString(%q, %s) = %q, want %q", c.value, c.kind, actual, c.expected)
+		}
+	}
+}
+
+// maskedBizService 仅支持分页查询的业务服务
+type maskedBizService struct {
+	BaseBizService[int64, testMaskedRecord, testMaskedRecord, testMaskedRecord, testMaskedRecord]
+	BaseBizServiceContext[int64, testMaskedRecord, testMaskedRecord]
+}
+
+func (maskedBizService) BaseQueryByPagerContext(_ context.Context, _ map[string]any, pager *Pager[testMaskedRecord]) error {
+	pager.Records = []*testMaskedRecord{{ID: 1, Phone: "13812345678", Email: "alice@example.com", Note: "n"}}
+	pager.Total = 1
+	return nil
+}
+
+func TestQueryByPageMasking(t *testing.T) {
+	type masked struct {
+		Records []map[string]any `json:"records"`
+		Total   int64            `json:"total"`
+	}
+	cases := []struct {
+		name        string
+		permissions []string
+		phone       string
+		email       string
+		note        bool
+	}{
+		{"no permission", nil, "138****5678", "a***@example.com", false},
+		{"default permission", []string{"user:pii"}, "13812345678", "a***@example.com", true},
+		{"field permission", []string{"user:email"}, "138****5678", "alice@example.com", false},
+		{"all permissions", []string{"user:pii", "user:email"}, "13812345678", "alice@example.com", true},
+	}
+	for _, c := range cases {
+		router := NewBaseRouter[int64, testMaskedRecord, testMaskedRecord, testMaskedRecord, testMaskedRecord](maskedBizService{}).
+			SetMaskPermission("user:pii")
+		router.authorityFetch = func(*ginstarter.Request) Authority[int64] {
+			return &testPermissionAuthority{testAuthority: testAuthority{id: 1}, permissions: c.permissions}
+		}
+		result := serve(t, router.QueryByPage(), testCall{body: `{"size":10,"number":1}`})
+		if result.status != ginstarter.StatusCodeSuccess {
+			t.Fatalf("%s: query failed: %d %s", c.name, result.status, result.message)
+		}
+		var pager masked
+		result.decodeData(t, &pager)
+		if len(pager.Records) != 1 || pager.Total != 1 {
+			t.Fatalf("%s: unexpected pager: %+v", c.name, pager)
+		}
+		record := pager.Records[0]
+		if record["phone"] != c.phone || record["email"] != c.email {
+			t.Errorf("%s: phone %v email %v, want %s %s", c.name, record["phone"], record["email"], c.phone, c.email)
+		}
+		// 拥有默认权限时不移除字段
+		if _, ok := record["note"]; ok != c.note {
+			t.Errorf("%s: note returned %v, want %v", c.name, ok, c.note)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package webcloud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/util/json"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const ctxKeyRows = "_webcloud_rows"
+
+const (
+	ResultSuccess     = "success"      // 执行成功
+	ResultBizError    = "biz_error"    // 业务错误
+	ResultClientError = "client_error" // 请求错误 4xx
+	ResultServerError = "server_error" // 服务错误 5xx 返回错误或非ginstarter中断业务的panic
+)
+
+var (
+	defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	defaultRowBuckets     = []float64{0, 1, 5, 10, 50, 100, 500, 1000}
+	defaultSizeBuckets    = []float64{128, 512, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// histogram 累计直方图
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+type metricKey struct {
+	router    string
+	operation Operation
+}
+
+type requestMetricKey struct {
+	metricKey
+	result string
+}
+
+// MetricsRegistry 基础路由的指标 以Prometheus文本格式输出 多个路由可共用
+type MetricsRegistry struct {
+	mutex        sync.Mutex
+	requests     map[requestMetricKey]uint64
+	latency      map[metricKey]*histogram
+	rows         map[metricKey]*histogram
+	requestSize  map[metricKey]*histogram
+	responseSize map[metricKey]*histogram
+}
+
+// NewMetricsRegistry 创建指标注册表
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		requests:     make(map[requestMetricKey]uint64),
+		latency:      make(map[metricKey]*histogram),
+		rows:         make(map[metricKey]*histogram),
+		requestSize:  make(map[metricKey]*histogram),
+		responseSize: make(map[metricKey]*histogram),
+	}
+}
+
+func observe(series map[metricKey]*histogram, key metricKey, buckets []float64, value float64) {
+	h := series[key]
+	if h == nil {
+		h = newHistogram(buckets)
+		series[key] = h
+	}
+	h.observe(value)
+}
+
+// record 记录一次基础操作
+func (m *MetricsRegistry) record(key metricKey, result string, latency time.Duration, rows, requestSize, responseSize int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests[requestMetricKey{metricKey: key, result: result}]++
+	observe(m.latency, key, defaultLatencyBuckets, latency.Seconds())
+	if rows >= 0 {
+		observe(m.rows, key, defaultRowBuckets, float64(rows))
+	}
+	if requestSize >= 0 {
+		observe(m.requestSize, key, defaultSizeBuckets, float64(requestSize))
+	}
+	if responseSize >= 0 {
+		observe(m.responseSize, key, defaultSizeBuckets, float64(responseSize))
+	}
+}
+
+// WriteText 以Prometheus文本格式输出全部指标
+func (m *MetricsRegistry) WriteText() []byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var buf bytes.Buffer
+	buf.WriteString("# HELP webcloud_requests_total Total base router operations by result.\n")
+	buf.WriteString("# TYPE webcloud_requests_total counter\n")
+	requestKeys := make([]requestMetricKey, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		if requestKeys[i].metricKey != requestKeys[j].metricKey {
+			return lessMetricKey(requestKeys[i].metricKey, requestKeys[j].metricKey)
+		}
+		return requestKeys[i].result < requestKeys[j].result
+	})
+	for _, key := range requestKeys {
+		fmt.Fprintf(&buf, "webcloud_requests_total{%s,result=\"%s\"} %d\n", metricLabels(key.metricKey), escapeLabel(key.result), m.requests[key])
+	}
+	writeHistograms(&buf, "webcloud_request_duration_seconds", "Base router operation latency in seconds.", m.latency)
+	writeHistograms(&buf, "webcloud_rows_returned", "Rows returned by base router query operations.", m.rows)
+	writeHistograms(&buf, "webcloud_request_size_bytes", "Base router request body size in bytes.", m.requestSize)
+	writeHistograms(&buf, "webcloud_response_size_bytes", "Base router response body size in bytes.", m.responseSize)
+	return buf.Bytes()
+}
+
+func writeHistograms(buf *bytes.Buffer, name, help string, series map[metricKey]*histogram) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	keys := make([]metricKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessMetricKey(keys[i], keys[j]) })
+	for _, key := range keys {
+		h := series[key]
+		labels := metricLabels(key)
+		for i, bound := range h.buckets {
+			fmt.Fprintf(buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(buf, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(buf, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+func lessMetricKey(a, b metricKey) bool {
+	if a.router != b.router {
+		return a.router < b.router
+	}
+	return a.operation < b.operation
+}
+
+func metricLabels(key metricKey) string {
+	return fmt.Sprintf("router=\"%s\",operation=\"%s\"", escapeLabel(key.router), escapeLabel(string(key.operation)))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+// Handler 输出指标的Handler
+func (m *MetricsRegistry) Handler() ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+		response := ginstarter.RespTextPlain(m.WriteText())
+		response.Data().SetContentType("text/plain; version=0.0.4; charset=utf-8")
+		return response, nil
+	}
+}
+
+// MetricsRouter 输出指标的路由
+type MetricsRouter struct {
+	registry  *MetricsRegistry
+	groupPath string
+	path      string
+}
+
+// NewMetricsRouter 创建输出指标的路由 例: NewMetricsRouter(registry, "", "metrics")
+func NewMetricsRouter(registry *MetricsRegistry, groupPath, path string) *MetricsRouter {
+	return &MetricsRouter{registry: registry, groupPath: groupPath, path: path}
+}
+
+func (r *MetricsRouter) Info() *ginstarter.RouterInfo {
+	return &ginstarter.RouterInfo{
+		GroupPath: r.groupPath,
+	}
+}
+
+func (r *MetricsRouter) Handlers(router *ginstarter.RouterWrapper) {
+	router.GET(r.path, r.registry.Handler())
+}
+
+// SetMetrics 设置基础操作的指标注册表 以资源名称作为router标签
+func (b *BaseRouter[ID, S, M, Q, D]) SetMetrics(registry *MetricsRegistry) *BaseRouter[ID, S, M, Q, D] {
+	b.metrics = registry
+	return b
+}
+
+// observeRows 记录查询操作返回的数据条数 用于指标与追踪
+func (b *BaseRouter[ID, S, M, Q, D]) observeRows(request *ginstarter.Request, rows int) {
+	if b.metrics != nil || b.tracer != nil {
+		request.SetValue(ctxKeyRows, int64(rows))
+	}
+}
+
+// responseResult 获取响应的结果分类与状态码
+func responseResult(response ginstarter.Response, err error) (string, int) {
+	if err != nil {
+		return ResultServerError, int(ginstarter.StatusCodeException)
+	}
+	if response == nil || response.Data() == nil {
+		return ResultSuccess, int(ginstarter.StatusCodeSuccess)
+	}
+	status := json.NewGJsonBytes(response.Data().RawBody()).Get("status")
+	statusCode := int(status.Get("statusCode").IntResult())
+	switch {
+	case statusCode == 0 || statusCode == int(ginstarter.StatusCodeSuccess):
+		if status.Get("bizErrorCode").IntResult() != 0 {
+			return ResultBizError, int(ginstarter.StatusCodeSuccess)
+		}
+		return ResultSuccess, int(ginstarter.StatusCodeSuccess)
+	case statusCode >= 500:
+		return ResultServerError, statusCode
+	default:
+		return ResultClientError, statusCode
+	}
+}
+
+// ginstarterPanicType request.Panic 抛出的ginstarter内部类型
+var ginstarterPanicType = reflect.TypeOf(recoverValue(func() {
+	(*ginstarter.Request)(nil).Panic(int(ginstarter.StatusCodeException), nil)
+}))
+
+func recoverValue(fn func()) (recovered any) {
+	defer func() { recovered = recover() }()
+	fn()
+	return nil
+}
+
+// panicResult 获取panic的结果分类与状态码
+// 经由 AbortRequest 中断的请求按记录的状态码分类 其他panic为服务错误
+func panicResult(abort *RequestAbort, recovered any) (string, int) {
+	statusCode := int(ginstarter.StatusCodeException)
+	if abort != nil && abort.StatusCode > 0 {
+		statusCode = abort.StatusCode
+	} else if status, ok := ginstarterPanicStatus(recovered); ok {
+		statusCode = status
+	}
+	if statusCode >= 500 {
+		return ResultServerError, statusCode
+	}
+	return ResultClientError, statusCode
+}
+
+// ginstarterPanicStatus 获取第三方代码直接调用 request.Panic 时携带的状态码
+// ginstarter未公开该状态码 依赖其内部字段 字段变更时返回false 由测试保证与当前依赖版本一致
+func ginstarterPanicStatus(recovered any) (int, bool) {
+	if recovered == nil || reflect.TypeOf(recovered) != ginstarterPanicType || ginstarterPanicType.Kind() != reflect.Pointer {
+		return 0, false
+	}
+	field := reflect.ValueOf(recovered).Elem().FieldByName("statusCode")
+	if !field.IsValid() || !field.CanInt() || field.Int() <= 0 {
+		return 0, false
+	}
+	return int(field.Int()), true
+}
+
+// requestSize 获取请求体大小 取已读取的字节数、缓存的请求体与请求声明的长度中的最大值 均未知时返回-1
+// 分块传输的请求声明长度为-1 按实际读取的字节数记录
+func requestSize(counter *countingReader, body []byte, contentLength int64) int64 {
+	size := contentLength
+	if counter != nil && counter.n > size {
+		size = counter.n
+	}
+	if body != nil && int64(len(body)) > size {
+		size = int64(len(body))
+	}
+	return size
+}
+
+// countingReader 统计已读取的请求体字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// measure 记录基础操作的指标
+func (b *BaseRouter[ID, S, M, Q, D]) measure(request *ginstarter.Request, operation Operation, handler ginstarter.HandlerWrapper) (response ginstarter.Response, err error) {
+	if b.metrics == nil {
+		return handler(request)
+	}
+	start := time.Now()
+	var counter *countingReader
+	if rawRequest := request.RawGinContext().Request; rawRequest.Body != nil && rawRequest.Body != http.NoBody {
+		counter = &countingReader{ReadCloser: rawRequest.Body}
+		rawRequest.Body = counter
+	}
+	completed := false
+	defer func() {
+		result := ResultServerError
+		var recovered any
+		if completed {
+			result, _ = responseResult(response, err)
+		} else if recovered = recover(); recovered != nil {
+			result, _ = panicResult(requestAbort(request), recovered)
+		}
+		rows := int64(-1)
+		if v, ok := request.GetValue(ctxKeyRows); ok {
+			rows = v.(int64)
+		}
+		responseSize := int64(-1)
+		if completed && response != nil && response.Data() != nil {
+			responseSize = int64(len(response.Data().RawBody()))
+		}
+		b.metrics.record(metricKey{router: b.resource, operation: operation}, result, time.Since(start),
+			rows, requestSize(counter, cachedBody(request), request.RawGinContext().Request.ContentLength), responseSize)
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+	response, err = handler(request)
+	completed = true
+	return
+}
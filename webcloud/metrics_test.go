@@ -0,0 +1,78 @@
+package webcloud
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+func TestPanicResult(t *testing.T) {
+	var request *ginstarter.Request
+	cases := []struct {
+		name   string
+		abort  *RequestAbort
+		panic  func()
+		result string
+		status int
+	}{
+		{"aborted forbidden", &RequestAbort{StatusCode: http.StatusForbidden}, func() { request.Panic(http.StatusForbidden, nil) }, ResultClientError, http.StatusForbidden},
+		{"aborted unavailable", &RequestAbort{StatusCode: http.StatusServiceUnavailable}, func() { request.Panic(http.StatusServiceUnavailable, nil) }, ResultServerError, http.StatusServiceUnavailable},
+		{"unauthorized", nil, func() { request.Panic(http.StatusUnauthorized, errors.New("token expired")) }, ResultClientError, http.StatusUnauthorized},
+		{"bad request", nil, func() { request.Panic(http.StatusBadRequest, errors.New("bad json")) }, ResultClientError, http.StatusBadRequest},
+		{"runtime", nil, func() { panic(errors.New("nil pointer")) }, ResultServerError, http.StatusInternalServerError},
+		{"string", nil, func() { panic("boom") }, ResultServerError, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		result, status := panicResult(c.abort, recoverValue(c.panic))
+		if result != c.result || status != c.status {
+			t.Errorf("%s: panicResult = %s %d, want %s %d", c.name, result, status, c.result, c.status)
+		}
+	}
+}
+
+// TestGinstarterPanicStatusField 第三方 request.Panic 的分类依赖ginstarter内部字段 升级ginstarter后字段变更时该测试失败
+func TestGinstarterPanicStatusField(t *testing.T) {
+	if ginstarterPanicType == nil || ginstarterPanicType.Kind() != reflect.Pointer ||
+		!strings.HasSuffix(ginstarterPanicType.Elem().PkgPath(), "ginstarter") {
+		t.Fatalf("request.Panic no longer panics with a ginstarter pointer type: %v", ginstarterPanicType)
+	}
+	field, ok := ginstarterPanicType.Elem().FieldByName("statusCode")
+	if !ok || field.Type.Kind() != reflect.Int {
+		t.Fatalf("ginstarter panic type %v lost its int statusCode field; update ginstarterPanicStatus", ginstarterPanicType)
+	}
+	var request *ginstarter.Request
+	status, ok := ginstarterPanicStatus(recoverValue(func() { request.Panic(http.StatusConflict, nil) }))
+	if !ok || status != http.StatusConflict {
+		t.Fatalf("ginstarterPanicStatus = %d %v, want %d", status, ok, http.StatusConflict)
+	}
+}
+
+func TestRequestSize(t *testing.T) {
+	chunked := &countingReader{ReadCloser: io.NopCloser(strings.NewReader("0123456789"))}
+	if _, err := io.ReadAll(chunked); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name          string
+		counter       *countingReader
+		body          []byte
+		contentLength int64
+		size          int64
+	}{
+		{"chunked read", chunked, nil, -1, 10},
+		{"chunked cached", &countingReader{ReadCloser: http.NoBody}, []byte("01234"), -1, 5},
+		{"declared unread", &countingReader{ReadCloser: http.NoBody}, nil, 20, 20},
+		{"no body", nil, nil, 0, 0},
+		{"unknown", nil, nil, -1, -1},
+	}
+	for _, c := range cases {
+		if size := requestSize(c.counter, c.body, c.contentLength); size != c.size {
+			t.Errorf("%s: requestSize = %d, want %d", c.name, size, c.size)
+		}
+	}
+}
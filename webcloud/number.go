@@ -0,0 +1,124 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"fmt"
+	goreflect "reflect"
+	"strconv"
+
+	"github.com/acexy/golang-toolkit/util/reflect"
+	"github.com/acexy/golang-toolkit/util/str"
+)
+
+// NumberFieldError 请求中的数值无法转换为对应字段的类型
+type NumberFieldError struct {
+	Field string // 请求字段名
+	Value string // 请求的数值
+	Type  string // 字段类型
+}
+
+func (e *NumberFieldError) Error() string {
+	return fmt.Sprintf("field %s: %s is not a valid %s", e.Field, e.Value, e.Type)
+}
+
+// columnTypes 获取结构体各数据库字段名对应的字段类型
+func columnTypes(value any) map[string]goreflect.Type {
+	fieldValues, err := reflect.AllFieldValue(value)
+	if err != nil {
+		panic(err)
+	}
+	result := make(map[string]goreflect.Type, len(fieldValues))
+	for fieldName, fieldValue := range fieldValues {
+		if fieldValue != nil {
+			result[structName2Column(fieldName)] = goreflect.TypeOf(fieldValue)
+		}
+	}
+	return result
+}
+
+// convertNumbers 将请求中的json.Number按查询结构体Q或修改结构体M的字段类型转换 避免大整数精度丢失
+// 无对应字段或字段非数值类型时 整数转换为int64/uint64 其余转换为float64
+func (b *BaseRouter[ID, S, M, Q, D]) convertNumbers(param map[string]any, m Mode) error {
+	types := b.queryColumnTypes
+	if m == ModeModify {
+		types = b.modifyColumnTypes
+	}
+	for key, value := range param {
+		converted, err := convertNumber(key, value, types[str.CamelToSnake(key)], b.stringIDs)
+		if err != nil {
+			return err
+		}
+		param[key] = converted
+	}
+	return nil
+}
+
+// convertNumber 转换单个请求值 切片(IN条件)逐个转换 stringIDs为true时64位整数字段同时接受字符串
+func convertNumber(key string, value any, target goreflect.Type, stringIDs bool) (any, error) {
+	switch v := value.(type) {
+	case sdkjson.Number:
+		return convertJsonNumber(key, v, target)
+	case string:
+		if stringIDs && target != nil && target.Kind() != goreflect.Slice && isInt64Type(target) {
+			return convertJsonNumber(key, sdkjson.Number(v), target)
+		}
+	case []any:
+		var elem goreflect.Type
+		if target != nil && target.Kind() == goreflect.Slice {
+			elem = target.Elem()
+		} else {
+			elem = target
+		}
+		for i, item := range v {
+			converted, err := convertNumber(key, item, elem, stringIDs)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	case map[string]any:
+		for k, item := range v {
+			converted, err := convertNumber(key, item, nil, stringIDs)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = converted
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+func convertJsonNumber(key string, number sdkjson.Number, target goreflect.Type) (any, error) {
+	for target != nil && target.Kind() == goreflect.Pointer {
+		target = target.Elem()
+	}
+	if target == nil {
+		return restoreSnapshotValue(number), nil
+	}
+	invalid := &NumberFieldError{Field: key, Value: number.String(), Type: target.String()}
+	switch target.Kind() {
+	case goreflect.Int, goreflect.Int8, goreflect.Int16, goreflect.Int32, goreflect.Int64:
+		v, err := strconv.ParseInt(number.String(), 10, target.Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		return goreflect.ValueOf(v).Convert(target).Interface(), nil
+	case goreflect.Uint, goreflect.Uint8, goreflect.Uint16, goreflect.Uint32, goreflect.Uint64:
+		v, err := strconv.ParseUint(number.String(), 10, target.Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		return goreflect.ValueOf(v).Convert(target).Interface(), nil
+	case goreflect.Float32, goreflect.Float64:
+		v, err := strconv.ParseFloat(number.String(), target.Bits())
+		if err != nil {
+			return nil, invalid
+		}
+		return goreflect.ValueOf(v).Convert(target).Interface(), nil
+	case goreflect.String:
+		return number.String(), nil
+	}
+	return restoreSnapshotValue(number), nil
+}
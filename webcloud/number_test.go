@@ -0,0 +1,99 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConvertJsonNumber(t *testing.T) {
+	cases := []struct {
+		name     string
+		number   string
+		target   reflect.Type
+		expected any
+	}{
+		{"max int64", "9223372036854775807", reflect.TypeFor[int64](), int64(9223372036854775807)},
+		{"above max int64 into uint64", "9223372036854775808", reflect.TypeFor[uint64](), uint64(9223372036854775808)},
+		{"max uint64", "18446744073709551615", reflect.TypeFor[uint64](), uint64(18446744073709551615)},
+		{"negative int", "-1", reflect.TypeFor[int32](), int32(-1)},
+		{"pointer target", "42", reflect.TypeFor[*int64](), int64(42)},
+		{"float", "1.5", reflect.TypeFor[float64](), 1.5},
+		{"string target", "9223372036854775808", reflect.TypeFor[string](), "9223372036854775808"},
+		{"no target int", "9223372036854775807", nil, int64(9223372036854775807)},
+		{"no target above max int64", "9223372036854775808", nil, uint64(9223372036854775808)},
+		{"no target float", "1.5", nil, 1.5},
+	}
+	for _, c := range cases {
+		v, err := convertJsonNumber("id", sdkjson.Number(c.number), c.target)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if v != c.expected {
+			t.Errorf("%s: got %#v, want %#v", c.name, v, c.expected)
+		}
+	}
+}
+
+func TestConvertJsonNumberInvalid(t *testing.T) {
+	cases := []struct {
+		name   string
+		number string
+		target reflect.Type
+	}{
+		{"above max int64", "9223372036854775808", reflect.TypeFor[int64]()},
+		{"negative into uint64", "-1", reflect.TypeFor[uint64]()},
+		{"negative into uint", "-9", reflect.TypeFor[uint]()},
+		{"overflow int8", "128", reflect.TypeFor[int8]()},
+		{"fraction into int", "1.5", reflect.TypeFor[int64]()},
+		{"above max uint64", "18446744073709551616", reflect.TypeFor[uint64]()},
+	}
+	for _, c := range cases {
+		_, err := convertJsonNumber("userId", sdkjson.Number(c.number), c.target)
+		var numberErr *NumberFieldError
+		if !errors.As(err, &numberErr) {
+			t.Errorf("%s: expected NumberFieldError, got %v", c.name, err)
+			continue
+		}
+		if numberErr.Field != "userId" || numberErr.Value != c.number || numberErr.Type != c.target.String() {
+			t.Errorf("%s: unexpected error detail %+v", c.name, numberErr)
+		}
+	}
+}
+
+func TestConvertNumberStringIDs(t *testing.T) {
+	target := reflect.TypeFor[int64]()
+	v, err := convertNumber("id", "9223372036854775807", target, true)
+	if err != nil || v != int64(9223372036854775807) {
+		t.Fatalf("string id not converted: %#v %v", v, err)
+	}
+	if v, _ = convertNumber("id", "9223372036854775807", target, false); v != "9223372036854775807" {
+		t.Fatalf("string id converted without stringIDs: %#v", v)
+	}
+	if v, _ = convertNumber("name", "12", reflect.TypeFor[string](), true); v != "12" {
+		t.Fatalf("string field converted: %#v", v)
+	}
+	if _, err = convertNumber("id", "abc", target, true); err == nil {
+		t.Fatal("non numeric string id should be rejected")
+	}
+	if _, err = convertNumber("id", "-1", reflect.TypeFor[uint64](), true); err == nil {
+		t.Fatal("negative string id into unsigned field should be rejected")
+	}
+}
+
+func TestConvertNumberSlice(t *testing.T) {
+	values := []any{sdkjson.Number("1"), "9223372036854775807"}
+	v, err := convertNumber("id", values, reflect.TypeFor[int64](), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []any{int64(1), int64(9223372036854775807)}) {
+		t.Fatalf("IN condition not converted: %#v", v)
+	}
+	_, err = convertNumber("id", []any{sdkjson.Number("1"), sdkjson.Number("-1")}, reflect.TypeFor[[]uint64](), false)
+	if err == nil {
+		t.Fatal("negative element into unsigned slice should be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+package webcloud
+
+import (
+	"github.com/acexy/golang-toolkit/util/coll"
+)
+
+// PermissionAuthority 含有角色与权限的认证信息
+// Authority 的可选扩展 实现该接口的认证信息可用于基础操作的权限控制
+type PermissionAuthority interface {
+	// GetRoles 获取拥有的角色
+	GetRoles() []string
+	// GetPermissions 获取拥有的权限
+	GetPermissions() []string
+}
+
+// GetRoles 凭证不含角色信息
+func (c *ClientAuthority[ID]) GetRoles() []string {
+	return nil
+}
+
+// GetPermissions 凭证的权限范围即为其权限
+func (c *ClientAuthority[ID]) GetPermissions() []string {
+	return c.Scopes
+}
+
+// HasPermission 判断认证信息是否拥有全部指定的权限
+func HasPermission[ID IDType](authority Authority[ID], permissions ...string) bool {
+	if len(permissions) == 0 {
+		return true
+	}
+	permissionAuthority, ok := AuthorityAs[PermissionAuthority](authority)
+	if !ok {
+		return false
+	}
+	return coll.SliceIsSubset(permissions, permissionAuthority.GetPermissions())
+}
+
+// HasRole 判断认证信息是否拥有任一指定的角色
+func HasRole[ID IDType](authority Authority[ID], roles ...string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	permissionAuthority, ok := AuthorityAs[PermissionAuthority](authority)
+	if !ok {
+		return false
+	}
+	return len(coll.SliceIntersection(roles, permissionAuthority.GetRoles())) > 0
+}
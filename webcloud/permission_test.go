@@ -0,0 +1,83 @@
+package webcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+func TestHasPermissionAndRole(t *testing.T) {
+	authority := &testPermissionAuthority{testAuthority: testAuthority{id: 1}, roles: []string{"editor"}, permissions: []string{"a", "b"}}
+	wrapped := &SchemeAuthority[int64]{Authority: authority, Scheme: AuthSchemeBearer}
+	plain := &testAuthority{id: 2}
+	cases := []struct {
+		name      string
+		authority Authority[int64]
+		check     func(Authority[int64]) bool
+		expected  bool
+	}{
+		{"no permission required", plain, func(a Authority[int64]) bool { return HasPermission(a) }, true},
+		{"single permission", authority, func(a Authority[int64]) bool { return HasPermission(a, "a") }, true},
+		{"all permissions", authority, func(a Authority[int64]) bool { return HasPermission(a, "a", "b") }, true},
+		{"missing one permission", authority, func(a Authority[int64]) bool { return HasPermission(a, "a", "c") }, false},
+		{"wrapped permission", wrapped, func(a Authority[int64]) bool { return HasPermission(a, "b") }, true},
+		{"permission without PermissionAuthority", plain, func(a Authority[int64]) bool { return HasPermission(a, "a") }, false},
+		{"permission without authority", nil, func(a Authority[int64]) bool { return HasPermission(a, "a") }, false},
+		{"no role required", plain, func(a Authority[int64]) bool { return HasRole(a) }, true},
+		{"any role", authority, func(a Authority[int64]) bool { return HasRole(a, "admin", "editor") }, true},
+		{"no matching role", authority, func(a Authority[int64]) bool { return HasRole(a, "admin") }, false},
+		{"wrapped role", wrapped, func(a Authority[int64]) bool { return HasRole(a, "editor") }, true},
+		{"role without PermissionAuthority", plain, func(a Authority[int64]) bool { return HasRole(a, "editor") }, false},
+	}
+	for _, c := range cases {
+		if actual := c.check(c.authority); actual != c.expected {
+			t.Errorf("%s: %v, want %v", c.name, actual, c.expected)
+		}
+	}
+}
+
+func TestRequirePermissionsAndRoles(t *testing.T) {
+	editor := &testPermissionAuthority{testAuthority: testAuthority{id: 1}, roles: []string{"editor"}, permissions: []string{"record:read"}}
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](newMemoryBizService(testRecord{ID: 1})).
+		RequirePermissions(OperationQueryByID, "record:read").
+		RequirePermissions(OperationRemoveByID, "record:read", "record:remove").
+		RequireRoles(OperationQueryOne, "admin", "editor").
+		RequireRoles(OperationQuery, "admin")
+	router.authorityFetch = func(*ginstarter.Request) Authority[int64] { return editor }
+	cases := []struct {
+		name    string
+		handler ginstarter.HandlerWrapper
+		call    testCall
+		status  ginstarter.StatusCode
+	}{
+		{"has permission", router.QueryById(), testCall{method: http.MethodGet}, ginstarter.StatusCodeSuccess},
+		{"missing one of permissions", router.RemoveById(), testCall{method: http.MethodDelete}, ginstarter.StatusCodeForbidden},
+		{"has any role", router.QueryOne(), testCall{body: `{"name":"a"}`}, ginstarter.StatusCodeSuccess},
+		{"missing role", router.Query(), testCall{body: `{"name":"a"}`}, ginstarter.StatusCodeForbidden},
+		{"custom handler permission", router.WithPermissions(fetchHandler(router.authorityFetch), "record:remove"), testCall{}, ginstarter.StatusCodeForbidden},
+	}
+	for _, c := range cases {
+		c.call.params = map[string]string{"id": "1"}
+		if result := serve(t, c.handler, c.call); result.status != c.status {
+			t.Errorf("%s: status %d %s, want %d", c.name, result.status, result.message, c.status)
+		}
+	}
+}
+
+func TestCheckOperationWithoutAuthorityFetch(t *testing.T) {
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](newMemoryBizService(testRecord{ID: 1}))
+	handlers := map[string]ginstarter.HandlerWrapper{
+		"permissions": router.RequirePermissions(OperationQueryByID, "record:read").QueryById(),
+		"schemes":     router.AcceptAuthSchemes([]AuthScheme{AuthSchemeBearer}, OperationQueryOne).QueryOne(),
+		"with schemes": router.WithAuthSchemes(func(*ginstarter.Request) (ginstarter.Response, error) {
+			return ginstarter.RespRestSuccess(), nil
+		}, AuthSchemeBearer),
+	}
+	for name, handler := range handlers {
+		result := serve(t, handler, testCall{params: map[string]string{"id": "1"}, body: `{"name":"a"}`})
+		if result.status != ginstarter.StatusCodeUnauthorized || result.message != errAuthorityFetchMissing.Error() {
+			t.Errorf("%s: status %d %q, want 401 %q", name, result.status, result.message, errAuthorityFetchMissing.Error())
+		}
+	}
+}
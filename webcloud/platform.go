@@ -0,0 +1,79 @@
+package webcloud
+
+import (
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/str"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// PlatformRule 平台规则 为指定平台的请求覆盖路由默认的字段安全设置与数据权限控制
+// 请求字段不在生效的字段安全设置中时 保存、修改、查询均响应参数错误
+type PlatformRule struct {
+	SaveAllowedFields   []string // 允许自由保存的结构体字段名 为空时使用路由默认设置
+	ModifyAllowedFields []string // 允许自由更新的结构体字段名 为空时使用路由默认设置
+	QueryAllowedFields  []string // 允许自由查询的结构体字段名 为空时使用路由默认设置
+	DataLimitFieldName  string   // 数据权限控制字段 为空时使用路由默认设置
+	DisableDataLimit    bool     // 该平台的请求不进行数据权限控制
+}
+
+// platformRule 已转换为数据库字段的平台规则
+type platformRule struct {
+	columnAllowlist
+	dataLimitColumn  string
+	disableDataLimit bool
+}
+
+func newPlatformRule(rule PlatformRule) *platformRule {
+	result := &platformRule{
+		columnAllowlist: newColumnAllowlist(FieldAllowlist{
+			SaveAllowedFields:   rule.SaveAllowedFields,
+			ModifyAllowedFields: rule.ModifyAllowedFields,
+			QueryAllowedFields:  rule.QueryAllowedFields,
+		}),
+		disableDataLimit: rule.DisableDataLimit,
+	}
+	if rule.DataLimitFieldName != "" {
+		result.dataLimitColumn = str.CamelToSnake(str.LowFirstChar(rule.DataLimitFieldName))
+		// 与路由默认设置一致 数据权限控制字段总是允许查询
+		if len(result.queryAllowedColumns) > 0 && !coll.SliceContains(result.queryAllowedColumns, result.dataLimitColumn) {
+			result.queryAllowedColumns = append(result.queryAllowedColumns, result.dataLimitColumn)
+		}
+	}
+	return result
+}
+
+// AllowPlatforms 限定可执行基础操作的平台 未指定操作时作用于全部基础操作
+// 其他平台的请求响应403
+func (b *BaseRouter[ID, S, M, Q, D]) AllowPlatforms(platforms []Platform, operations ...Operation) *BaseRouter[ID, S, M, Q, D] {
+	if len(operations) == 0 {
+		operations = AllOperations
+	}
+	if b.operationPlatforms == nil {
+		b.operationPlatforms = make(map[Operation][]Platform)
+	}
+	for _, operation := range operations {
+		b.operationPlatforms[operation] = platforms
+	}
+	return b
+}
+
+// SetPlatformRule 设置指定平台的规则 同一路由可为不同平台提供不同的字段安全设置与数据权限控制
+func (b *BaseRouter[ID, S, M, Q, D]) SetPlatformRule(platform Platform, rule PlatformRule) *BaseRouter[ID, S, M, Q, D] {
+	if b.platformRules == nil {
+		b.platformRules = make(map[Platform]*platformRule)
+	}
+	b.platformRules[platform] = newPlatformRule(rule)
+	return b
+}
+
+// getPlatformRule 获取当前请求所属平台的规则
+func (b *BaseRouter[ID, S, M, Q, D]) getPlatformRule(request *ginstarter.Request) *platformRule {
+	if len(b.platformRules) == 0 || b.authorityFetch == nil {
+		return nil
+	}
+	authority := b.GetAuthorityData(request, true)
+	if authority == nil {
+		return nil
+	}
+	return b.platformRules[authority.GetPlatform()]
+}
@@ -0,0 +1,44 @@
+package webcloud
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlatformSaveAllowedFields(t *testing.T) {
+	router := &testRouter{tenantColumn: "tenant_id"}
+	rule := newPlatformRule(PlatformRule{SaveAllowedFields: []string{"ID", "Name", "TenantID"}})
+	allowed := rule.columns(ModeSave)
+	cases := []struct {
+		name  string
+		param map[string]any
+		pass  bool
+	}{
+		{"allowed", map[string]any{"name": "a"}, true},
+		{"not in rule", map[string]any{"name": "a", "userId": 1}, false},
+		{"forbidden id", map[string]any{"id": 1}, false},
+		{"tenant", map[string]any{"tenantId": 1}, false},
+	}
+	for _, c := range cases {
+		if got := router.checkColumns(allowed, c.param, ModeSave); got != c.pass {
+			t.Errorf("%s: checkColumns = %v, want %v", c.name, got, c.pass)
+		}
+	}
+}
+
+func TestPlatformDataLimitFieldQueryable(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    PlatformRule
+		columns []string
+	}{
+		{"appended", PlatformRule{QueryAllowedFields: []string{"Name"}, DataLimitFieldName: "TenantID"}, []string{"name", "tenant_id"}},
+		{"already allowed", PlatformRule{QueryAllowedFields: []string{"TenantID"}, DataLimitFieldName: "TenantID"}, []string{"tenant_id"}},
+		{"default allowlist", PlatformRule{DataLimitFieldName: "TenantID"}, nil},
+	}
+	for _, c := range cases {
+		if columns := newPlatformRule(c.rule).columns(ModeQuery); !slices.Equal(columns, c.columns) {
+			t.Errorf("%s: query columns %v, want %v", c.name, columns, c.columns)
+		}
+	}
+}
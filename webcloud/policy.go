@@ -0,0 +1,98 @@
+package webcloud
+
+import (
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/str"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// PolicyEffect 策略评估结果
+type PolicyEffect int8
+
+const (
+	PolicyAbstain PolicyEffect = iota // 策略不适用 交由后续策略评估
+	PolicyAllow                       // 允许 可附加条件
+	PolicyDeny                        // 拒绝
+)
+
+// PolicyContext 策略评估上下文
+type PolicyContext[ID IDType] struct {
+	Authority Authority[ID]
+	Operation Operation
+	Mode      Mode
+	Params    map[string]any // 请求参数 key为数据库字段名 查询为查询条件 修改为更新内容 保存为保存内容
+}
+
+// PolicyDecision 策略决定
+type PolicyDecision struct {
+	Effect     PolicyEffect
+	Conditions map[string]any // 允许时附加的条件 key为数据库字段名 与已有条件取交集 保存时作为强制设置的字段 不可覆盖数据权限控制字段
+	Reason     string         // 决定原因 用于日志
+}
+
+// Policy 行级数据策略 针对每个基础操作进行评估
+type Policy[ID IDType] interface {
+	Evaluate(ctx *PolicyContext[ID]) PolicyDecision
+}
+
+// PolicyFunc 函数形式的策略
+type PolicyFunc[ID IDType] func(ctx *PolicyContext[ID]) PolicyDecision
+
+func (f PolicyFunc[ID]) Evaluate(ctx *PolicyContext[ID]) PolicyDecision {
+	return f(ctx)
+}
+
+// SetPolicies 设置行级数据策略
+// 策略按顺序评估 首个非 PolicyAbstain 的决定生效 全部策略均不适用时 defaultDeny 决定是否拒绝
+func (b *BaseRouter[ID, S, M, Q, D]) SetPolicies(policies []Policy[ID], defaultDeny bool) *BaseRouter[ID, S, M, Q, D] {
+	b.policies = policies
+	b.policyDefaultDeny = defaultDeny
+	return b
+}
+
+// applyPolicies 评估行级数据策略 允许时将策略附加的条件合并至condition 拒绝时返回false
+// 附加的条件与condition中已有的条件冲突时 保存与按主键操作将被拒绝 条件查询将查询不到数据
+func (b *BaseRouter[ID, S, M, Q, D]) applyPolicies(request *ginstarter.Request, operation Operation, params, condition map[string]any) bool {
+	if len(b.policies) == 0 {
+		return true
+	}
+	ctx := &PolicyContext[ID]{
+		Authority: b.GetAuthorityData(request),
+		Operation: operation,
+		Mode:      operation.Mode(),
+		Params:    params,
+	}
+	for _, policy := range b.policies {
+		decision := policy.Evaluate(ctx)
+		switch decision.Effect {
+		case PolicyAllow:
+			// 附加的条件与已有条件取交集 不能放宽主键、数据权限控制与租户条件
+			if !mergeConditions(condition, decision.Conditions) && (operation == OperationSave || byIDOperation(operation)) {
+				logger.Logrus().Warningln("operation", operation, "denied, policy conditions conflict with existing conditions:", decision.Reason)
+				return false
+			}
+			return true
+		case PolicyDeny:
+			logger.Logrus().Warningln("operation", operation, "denied by policy:", decision.Reason)
+			return false
+		}
+	}
+	if b.policyDefaultDeny {
+		logger.Logrus().Warningln("operation", operation, "denied, no policy applied")
+		return false
+	}
+	return true
+}
+
+// structToColumns 将结构体转换为以数据库字段名为key的map 数值保留为json.Number 避免超出2^53的主键丢失精度
+func structToColumns(value any) map[string]any {
+	param, err := toJsonMap(value)
+	if err != nil {
+		logger.Logrus().Warningln("convert struct to map error:", err)
+		return nil
+	}
+	return coll.MapCollect(param, func(k string, v any) (string, any) {
+		return str.CamelToSnake(k), v
+	})
+}
@@ -0,0 +1,527 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/json"
+)
+
+// PolicyRule 表达式策略规则
+//
+// When 为条件表达式 支持:
+//   - 字面量: 'text' "text" 123 1.5 true false null [a, b]
+//   - 变量: authority.id authority.platform authority.roles authority.permissions authority.tenant authority.scheme
+//     operation mode param.<字段名>
+//   - 运算: == != < <= > >= in && || ! ()
+//
+// 例: "'editor' in authority.roles && param.status == 'draft'"
+//
+// Conditions 的值如果为 ${变量} 形式 将在评估时替换为变量值 例: {"userId": "${authority.id}"}
+type PolicyRule struct {
+	Operations []Operation     `json:"operations"` // 适用的基础操作 为空时适用于全部操作
+	When       string          `json:"when"`       // 条件表达式 为空时总是成立
+	Effect     string          `json:"effect"`     // allow 或 deny
+	Conditions map[string]any  `json:"conditions"` // 允许时附加的条件 key为字段名
+	Reason     string          `json:"reason"`     // 规则说明
+	when       exprNode        // 已编译的条件表达式
+	effect     PolicyEffect    // 已解析的效果
+	columns    map[string]any  // 已转换为数据库字段的条件
+	refs       map[string]bool // 条件中引用变量的数据库字段
+}
+
+// ExpressionPolicy 基于表达式规则的策略 规则按顺序匹配 首个条件成立的规则生效 无规则成立时不适用
+// 规则可来自配置文件 无需重新编译
+type ExpressionPolicy[ID IDType] struct {
+	rules []*PolicyRule
+}
+
+// NewExpressionPolicy 通过规则创建表达式策略
+func NewExpressionPolicy[ID IDType](rules []PolicyRule) (*ExpressionPolicy[ID], error) {
+	policy := &ExpressionPolicy[ID]{}
+	for i := range rules {
+		rule := rules[i]
+		switch strings.ToLower(rule.Effect) {
+		case "allow":
+			rule.effect = PolicyAllow
+		case "deny":
+			rule.effect = PolicyDeny
+		default:
+			return nil, fmt.Errorf("policy rule %d: unknown effect %q", i, rule.Effect)
+		}
+		if strings.TrimSpace(rule.When) != "" {
+			node, err := compileExpr(rule.When)
+			if err != nil {
+				return nil, fmt.Errorf("policy rule %d: %w", i, err)
+			}
+			rule.when = node
+		}
+		rule.columns = make(map[string]any, len(rule.Conditions))
+		rule.refs = make(map[string]bool)
+		for field, value := range rule.Conditions {
+			column := structName2Column(field)
+			if ref, ok := exprVariableRef(value); ok {
+				if err := checkExprVariable(ref); err != nil {
+					return nil, fmt.Errorf("policy rule %d: %w", i, err)
+				}
+				rule.refs[column] = true
+				value = ref
+			}
+			rule.columns[column] = value
+		}
+		policy.rules = append(policy.rules, &rule)
+	}
+	return policy, nil
+}
+
+// ParseExpressionPolicy 通过json规则列表创建表达式策略
+func ParseExpressionPolicy[ID IDType](rulesJson []byte) (*ExpressionPolicy[ID], error) {
+	var rules []PolicyRule
+	if err := json.ParseBytesError(rulesJson, &rules); err != nil {
+		return nil, err
+	}
+	return NewExpressionPolicy[ID](rules)
+}
+
+func (e *ExpressionPolicy[ID]) Evaluate(ctx *PolicyContext[ID]) PolicyDecision {
+	env := func(path string) any {
+		return policyVariable(ctx, path)
+	}
+	for _, rule := range e.rules {
+		if len(rule.Operations) > 0 && !coll.SliceContains(rule.Operations, ctx.Operation) {
+			continue
+		}
+		if rule.when != nil && !exprTruthy(rule.when(env)) {
+			continue
+		}
+		conditions := make(map[string]any, len(rule.columns))
+		for column, value := range rule.columns {
+			if rule.refs[column] {
+				value = env(value.(string))
+			}
+			conditions[column] = value
+		}
+		return PolicyDecision{
+			Effect:     rule.effect,
+			Conditions: conditions,
+			Reason:     rule.Reason,
+		}
+	}
+	return PolicyDecision{Effect: PolicyAbstain}
+}
+
+// policyVariable 获取表达式变量的值
+func policyVariable[ID IDType](ctx *PolicyContext[ID], path string) any {
+	switch path {
+	case "operation":
+		return string(ctx.Operation)
+	case "mode":
+		return ctx.Mode.String()
+	}
+	if field, ok := strings.CutPrefix(path, "param."); ok {
+		return ctx.Params[structName2Column(field)]
+	}
+	authority := ctx.Authority
+	if authority == nil {
+		return nil
+	}
+	switch path {
+	case "authority.id":
+		return authority.GetIdentityID()
+	case "authority.platform":
+		return string(authority.GetPlatform())
+	case "authority.roles":
+		if permissionAuthority, ok := AuthorityAs[PermissionAuthority](authority); ok {
+			return permissionAuthority.GetRoles()
+		}
+	case "authority.permissions":
+		if permissionAuthority, ok := AuthorityAs[PermissionAuthority](authority); ok {
+			return permissionAuthority.GetPermissions()
+		}
+	case "authority.tenant":
+		tenantID, _ := GetTenantID(authority)
+		return tenantID
+	case "authority.scheme":
+		scheme, _ := GetAuthScheme(authority)
+		return string(scheme)
+	}
+	return nil
+}
+
+func checkExprVariable(path string) error {
+	switch path {
+	case "operation", "mode", "authority.id", "authority.platform", "authority.roles",
+		"authority.permissions", "authority.tenant", "authority.scheme":
+		return nil
+	}
+	if strings.HasPrefix(path, "param.") && len(path) > len("param.") {
+		return nil
+	}
+	return fmt.Errorf("unknown variable %q", path)
+}
+
+func exprVariableRef(value any) (string, bool) {
+	text, ok := value.(string)
+	if !ok || !strings.HasPrefix(text, "${") || !strings.HasSuffix(text, "}") {
+		return "", false
+	}
+	return strings.TrimSpace(text[2 : len(text)-1]), true
+}
+
+// ---------- 表达式解析
+
+type exprNode func(env func(path string) any) any
+
+type exprTokenKind int8
+
+const (
+	tokenEOF exprTokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	value string
+	pos   int
+}
+
+type exprParser struct {
+	tokens []exprToken
+	index  int
+}
+
+func compileExpr(expr string) (exprNode, error) {
+	tokens, err := tokenizeExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	parser := &exprParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", token.value, token.pos)
+	}
+	return node, nil
+}
+
+func tokenizeExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, value: expr[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			start := i
+			i++
+			for i < len(expr) && (expr[i] >= '0' && expr[i] <= '9' || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, value: expr[start:i], pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(expr) && (expr[i] == '_' || expr[i] == '.' || expr[i] >= 'a' && expr[i] <= 'z' ||
+				expr[i] >= 'A' && expr[i] <= 'Z' || expr[i] >= '0' && expr[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, value: expr[start:i], pos: start})
+		default:
+			operator := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, value: operator, pos: i})
+			i += len(operator)
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.index]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.index]
+	if token.kind != tokenEOF {
+		p.index++
+	}
+	return token
+}
+
+func (p *exprParser) acceptOperator(operator string) bool {
+	if token := p.peek(); token.kind == tokenOperator && token.value == operator {
+		p.index++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(env func(string) any) any {
+			return exprTruthy(l(env)) || exprTruthy(r(env))
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(env func(string) any) any {
+			return exprTruthy(l(env)) && exprTruthy(r(env))
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.acceptOperator("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(env func(string) any) any {
+			return !exprTruthy(operand(env))
+		}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	token := p.peek()
+	var operator string
+	switch {
+	case token.kind == tokenOperator && coll.SliceContains([]string{"==", "!=", "<", "<=", ">", ">="}, token.value):
+		operator = token.value
+	case token.kind == tokenIdent && token.value == "in":
+		operator = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return func(env func(string) any) any {
+		l, r := left(env), right(env)
+		switch operator {
+		case "==":
+			return exprEqual(l, r)
+		case "!=":
+			return !exprEqual(l, r)
+		case "in":
+			return exprContains(r, l)
+		default:
+			cmp, ok := exprCompare(l, r)
+			if !ok {
+				return false
+			}
+			switch operator {
+			case "<":
+				return cmp < 0
+			case "<=":
+				return cmp <= 0
+			case ">":
+				return cmp > 0
+			default:
+				return cmp >= 0
+			}
+		}
+	}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenString:
+		value := token.value
+		return func(func(string) any) any { return value }, nil
+	case tokenNumber:
+		value := sdkjson.Number(token.value)
+		if _, ok := exprNumber(value); !ok {
+			return nil, fmt.Errorf("bad number %q at %d", token.value, token.pos)
+		}
+		return func(func(string) any) any { return value }, nil
+	case tokenIdent:
+		switch token.value {
+		case "true":
+			return func(func(string) any) any { return true }, nil
+		case "false":
+			return func(func(string) any) any { return false }, nil
+		case "null", "nil":
+			return func(func(string) any) any { return nil }, nil
+		}
+		if err := checkExprVariable(token.value); err != nil {
+			return nil, fmt.Errorf("%w at %d", err, token.pos)
+		}
+		path := token.value
+		return func(env func(string) any) any { return env(path) }, nil
+	case tokenOperator:
+		switch token.value {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptOperator(")") {
+				return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return node, nil
+		case "[":
+			var items []exprNode
+			if !p.acceptOperator("]") {
+				for {
+					item, err := p.parseOr()
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, item)
+					if p.acceptOperator("]") {
+						break
+					}
+					if !p.acceptOperator(",") {
+						return nil, fmt.Errorf("missing , or ] at %d", p.peek().pos)
+					}
+				}
+			}
+			return func(env func(string) any) any {
+				values := make([]any, len(items))
+				for i, item := range items {
+					values[i] = item(env)
+				}
+				return values
+			}, nil
+		}
+	}
+	if token.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", token.value, token.pos)
+}
+
+// ---------- 表达式求值
+
+func exprTruthy(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case string:
+		return value != ""
+	default:
+		return true
+	}
+}
+
+func exprNumber(v any) (*big.Float, bool) {
+	var text string
+	switch value := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		text = fmt.Sprint(value)
+	case float32:
+		text = strconv.FormatFloat(float64(value), 'g', -1, 32)
+	case float64:
+		text = strconv.FormatFloat(value, 'g', -1, 64)
+	case sdkjson.Number:
+		text = value.String()
+	default:
+		return nil, false
+	}
+	number, ok := new(big.Float).SetPrec(128).SetString(text)
+	return number, ok
+}
+
+// exprEqual 类型严格的相等比较 数值之间按数值比较 字符串与布尔仅与同类值相等 不同类型的值不相等
+func exprEqual(l, r any) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	ln, lok := exprNumber(l)
+	rn, rok := exprNumber(r)
+	if lok || rok {
+		return lok && rok && ln.Cmp(rn) == 0
+	}
+	lv, rv := reflect.ValueOf(l), reflect.ValueOf(r)
+	switch {
+	case lv.Kind() == reflect.String && rv.Kind() == reflect.String:
+		return lv.String() == rv.String()
+	case lv.Kind() == reflect.Bool && rv.Kind() == reflect.Bool:
+		return lv.Bool() == rv.Bool()
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func exprCompare(l, r any) (int, bool) {
+	ln, lok := exprNumber(l)
+	rn, rok := exprNumber(r)
+	if lok && rok {
+		return ln.Cmp(rn), true
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if lok && rok {
+		return strings.Compare(ls, rs), true
+	}
+	return 0, false
+}
+
+func exprContains(collection, item any) bool {
+	switch values := collection.(type) {
+	case []any:
+		return coll.SliceAnyContains(values, func(v any) bool { return exprEqual(v, item) })
+	case []string:
+		return coll.SliceAnyContains(values, func(v string) bool { return exprEqual(v, item) })
+	case string:
+		text, ok := item.(string)
+		return ok && strings.Contains(values, text)
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"testing"
+)
+
+type testPermissionAuthority struct {
+	testAuthority
+	roles       []string
+	permissions []string
+}
+
+func (a *testPermissionAuthority) GetRoles() []string       { return a.roles }
+func (a *testPermissionAuthority) GetPermissions() []string { return a.permissions }
+
+func evalExpr(t *testing.T, expr string, vars map[string]any) any {
+	t.Helper()
+	node, err := compileExpr(expr)
+	if err != nil {
+		t.Fatalf("compile %q: %v", expr, err)
+	}
+	return node(func(path string) any { return vars[path] })
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"param.a ==",
+		"'unterminated",
+		"param.a == 1)",
+		"(param.a == 1",
+		"[1, 2",
+		"[1 2]",
+		"param.a # 1",
+		"unknown.var == 1",
+		"param. == 1",
+		"1.2.3 == 1",
+		"param.a == 1 param.b",
+	}
+	for _, expr := range cases {
+		if _, err := compileExpr(expr); err == nil {
+			t.Errorf("compile %q: expected error", expr)
+		}
+	}
+}
+
+func TestEvalExpr(t *testing.T) {
+	vars := map[string]any{
+		"param.status":    "draft",
+		"param.amount":    sdkjson.Number("100"),
+		"param.big":       sdkjson.Number("9223372036854775808"),
+		"param.flag":      true,
+		"param.nothing":   nil,
+		"authority.id":    int64(7),
+		"authority.roles": []string{"editor", "viewer"},
+		"operation":       "query",
+	}
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"null", false},
+		{"param.status == 'draft'", true},
+		{`param.status == "draft"`, true},
+		{"param.status != 'draft'", false},
+		{"param.amount == 100", true},
+		{"param.amount == 100.0", true},
+		{"param.amount > 99.5", true},
+		{"param.amount >= 100", true},
+		{"param.amount < 100", false},
+		{"param.amount <= -1", false},
+		{"param.big > 9223372036854775807", true},
+		{"authority.id == 7", true},
+		{"param.flag == true", true},
+		{"param.flag", true},
+		{"!param.flag", false},
+		{"param.nothing == null", true},
+		{"param.missing == null", true},
+		{"'editor' in authority.roles", true},
+		{"'admin' in authority.roles", false},
+		{"param.status in ['draft', 'review']", true},
+		{"param.amount in [1, 100]", true},
+		{"'raf' in param.status", true},
+		{"'admin' in authority.roles || param.status == 'draft'", true},
+		{"'editor' in authority.roles && param.status == 'done'", false},
+		{"!('admin' in authority.roles) && (param.amount > 10 || false)", true},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"operation == 'query'", true},
+		{"'b' > 'a'", true},
+		{"param.status < 1", false},
+	}
+	for _, c := range cases {
+		if got := exprTruthy(evalExpr(t, c.expr, vars)); got != c.want {
+			t.Errorf("%s = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestExprEqualTypeStrict(t *testing.T) {
+	cases := []struct {
+		l, r any
+		want bool
+	}{
+		{"1", 1, false},
+		{1, "1", false},
+		{"1", sdkjson.Number("1"), false},
+		{"true", true, false},
+		{int64(1), sdkjson.Number("1"), true},
+		{uint64(1), 1.0, true},
+		{Platform("web"), "web", true},
+		{true, true, true},
+		{nil, nil, true},
+		{nil, "", false},
+		{[]any{1}, []any{1}, true},
+	}
+	for _, c := range cases {
+		if got := exprEqual(c.l, c.r); got != c.want {
+			t.Errorf("exprEqual(%#v, %#v) = %v, want %v", c.l, c.r, got, c.want)
+		}
+	}
+}
+
+func TestExpressionPolicyEvaluate(t *testing.T) {
+	policy, err := ParseExpressionPolicy[int64]([]byte(`[
+		{"operations": ["remove-by-id"], "effect": "deny", "reason": "no remove"},
+		{"when": "'editor' in authority.roles", "effect": "allow", "conditions": {"UserID": "${authority.id}", "Status": "draft"}},
+		{"when": "param.status == 'published'", "effect": "allow"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := &testPermissionAuthority{testAuthority: testAuthority{id: 7}, roles: []string{"editor"}}
+	decision := policy.Evaluate(&PolicyContext[int64]{Authority: editor, Operation: OperationQuery})
+	if decision.Effect != PolicyAllow || decision.Conditions["user_id"] != int64(7) || decision.Conditions["status"] != "draft" {
+		t.Fatalf("unexpected editor decision: %+v", decision)
+	}
+	decision = policy.Evaluate(&PolicyContext[int64]{Authority: editor, Operation: OperationRemoveByID})
+	if decision.Effect != PolicyDeny {
+		t.Fatalf("remove should be denied: %+v", decision)
+	}
+	viewer := &testPermissionAuthority{testAuthority: testAuthority{id: 8}}
+	decision = policy.Evaluate(&PolicyContext[int64]{Authority: viewer, Operation: OperationQuery, Params: map[string]any{"status": "published"}})
+	if decision.Effect != PolicyAllow || len(decision.Conditions) != 0 {
+		t.Fatalf("unexpected viewer decision: %+v", decision)
+	}
+	decision = policy.Evaluate(&PolicyContext[int64]{Authority: viewer, Operation: OperationQuery})
+	if decision.Effect != PolicyAbstain {
+		t.Fatalf("no rule should apply: %+v", decision)
+	}
+}
+
+func TestNewExpressionPolicyErrors(t *testing.T) {
+	cases := []PolicyRule{
+		{Effect: "maybe"},
+		{Effect: "allow", When: "param.a =="},
+		{Effect: "allow", Conditions: map[string]any{"UserID": "${unknown}"}},
+	}
+	for _, rule := range cases {
+		if _, err := NewExpressionPolicy[int64]([]PolicyRule{rule}); err == nil {
+			t.Errorf("rule %+v: expected error", rule)
+		}
+	}
+}
+
+func TestPolicyConditionsDoNotOverride(t *testing.T) {
+	condition := map[string]any{"id": int64(1), "user_id": int64(7), "tenant_id": int64(10)}
+	if mergeConditions(condition, map[string]any{"tenant_id": int64(11)}) {
+		t.Fatal("policy condition conflicting with tenant should be unsatisfiable")
+	}
+	condition = map[string]any{"id": int64(1), "user_id": int64(7)}
+	if !mergeConditions(condition, map[string]any{"user_id": int64(7), "status": "draft"}) {
+		t.Fatal("consistent policy condition should be satisfiable")
+	}
+	if condition["status"] != "draft" || condition["user_id"] != int64(7) {
+		t.Fatalf("unexpected merged condition: %v", condition)
+	}
+}
@@ -0,0 +1,79 @@
+package webcloud
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// policyRouter 以身份1访问 每次策略评估的上下文写入contexts
+func policyRouter(service *memoryBizService) (*testRouter, *[]PolicyContext[int64]) {
+	var contexts []PolicyContext[int64]
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service).
+		SetPolicies([]Policy[int64]{PolicyFunc[int64](func(ctx *PolicyContext[int64]) PolicyDecision {
+			contexts = append(contexts, *ctx)
+			return PolicyDecision{Effect: PolicyAllow}
+		})}, true)
+	router.authorityFetch = func(*ginstarter.Request) Authority[int64] { return &testAuthority{id: 1} }
+	return router, &contexts
+}
+
+func TestStructToColumnsKeepsLargeIntegers(t *testing.T) {
+	columns := structToColumns(&testRecord{ID: 1<<53 + 1, UserID: 2, Name: "a"})
+	if fmt.Sprint(columns["id"]) != "9007199254740993" || fmt.Sprint(columns["user_id"]) != "2" || columns["name"] != "a" {
+		t.Fatalf("unexpected columns: %v", columns)
+	}
+}
+
+func TestSavePolicyParams(t *testing.T) {
+	router, contexts := policyRouter(newMemoryBizService())
+	result := serve(t, router.Save(), testCall{body: `{"userId":9007199254740993,"name":"a"}`})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("save failed: %d %s", result.status, result.message)
+	}
+	if len(*contexts) != 1 {
+		t.Fatalf("policy evaluated %d times", len(*contexts))
+	}
+	params := (*contexts)[0].Params
+	if fmt.Sprint(params["user_id"]) != "9007199254740993" || params["name"] != "a" {
+		t.Fatalf("unexpected save policy params: %v", params)
+	}
+}
+
+func TestModifyPolicyParams(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1, UserID: 1, Name: "a"})
+	router, contexts := policyRouter(service)
+	result := serve(t, router.ModifyById(), testCall{params: map[string]string{"id": "1"}, body: `{"userId":2,"name":"b"}`})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("modify failed: %d %s", result.status, result.message)
+	}
+	if len(*contexts) != 1 {
+		t.Fatalf("policy evaluated %d times", len(*contexts))
+	}
+	params := (*contexts)[0].Params
+	if _, ok := params["userId"]; ok || fmt.Sprint(params["user_id"]) != "2" || params["name"] != "b" {
+		t.Fatalf("modify policy params should be keyed by column: %v", params)
+	}
+	if update := service.lastCall(t, "ModifyByID").update; fmt.Sprint(update["userId"]) != "2" {
+		t.Fatalf("unexpected update passed to service: %v", update)
+	}
+}
+
+func TestModifyPolicyDeniesByColumn(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1, UserID: 1, Name: "a"})
+	policy, err := NewExpressionPolicy[int64]([]PolicyRule{{Operations: []Operation{OperationModifyByID}, When: "param.user_id != null", Effect: "deny"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service).SetPolicies([]Policy[int64]{policy}, false)
+	router.authorityFetch = func(*ginstarter.Request) Authority[int64] { return &testAuthority{id: 1} }
+	result := serve(t, router.ModifyById(), testCall{params: map[string]string{"id": "1"}, body: `{"userId":2}`})
+	if result.status != ginstarter.StatusCodeForbidden {
+		t.Fatalf("policy on user_id should deny modify: %d", result.status)
+	}
+	result = serve(t, router.ModifyById(), testCall{params: map[string]string{"id": "1"}, body: `{"name":"b"}`})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("modify without user_id should pass: %d %s", result.status, result.message)
+	}
+}
@@ -0,0 +1,296 @@
+package webcloud
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm int8
+
+const (
+	RateLimitTokenBucket RateLimitAlgorithm = iota // 令牌桶 允许突发 Limit 个请求 每 Window 补满
+	RateLimitFixedWindow                           // 固定窗口 每 Window 最多 Limit 个请求 可用于配额
+)
+
+// RateLimitRule 限流规则
+type RateLimitRule struct {
+	Name       string             // 规则名称 不同规则的计数相互独立
+	Algorithm  RateLimitAlgorithm // 限流算法
+	Limit      int                // 窗口内允许的请求数 令牌桶时为桶容量
+	Window     time.Duration      // 窗口长度 令牌桶时为补满的时长
+	ByIdentity bool               // 按认证身份分别计数 无认证信息时按客户端IP
+	ByPlatform bool               // 按平台分别计数
+	ByEndpoint bool               // 按基础操作或自定义接口分别计数
+	Platforms  []Platform         // 仅作用于指定平台 为空时作用于全部请求
+}
+
+// RateLimitResult 单次限流检查的结果
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration // 距离配额恢复的时长
+}
+
+// RateLimitStore 限流计数存储 分布式部署时可基于共享存储实现
+type RateLimitStore interface {
+	// Take 同时消耗各规则的一次配额 keys与rules一一对应 任一规则超出限流时均不消耗配额
+	Take(keys []string, rules []RateLimitRule, now time.Time) ([]RateLimitResult, error)
+}
+
+type rateLimitState struct {
+	tokens  float64   // 令牌桶剩余令牌 固定窗口已用次数
+	last    time.Time // 令牌桶上次补充时间 固定窗口起始时间
+	expires time.Time
+}
+
+// MemoryRateLimitStore 基于内存的限流计数存储
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	states    map[string]*rateLimitState
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore 创建基于内存的限流计数存储
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{states: make(map[string]*rateLimitState)}
+}
+
+func (m *MemoryRateLimitStore) Take(keys []string, rules []RateLimitRule, now time.Time) ([]RateLimitResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sweep(now)
+	states := make([]*rateLimitState, len(rules))
+	allowed := true
+	for i, rule := range rules {
+		states[i] = m.refresh(keys[i], rule, now)
+		if !states[i].available(rule) {
+			allowed = false
+		}
+	}
+	results := make([]RateLimitResult, len(rules))
+	for i, rule := range rules {
+		state := states[i]
+		result := RateLimitResult{Limit: rule.Limit, Allowed: allowed}
+		if allowed {
+			state.take(rule)
+		}
+		switch rule.Algorithm {
+		case RateLimitFixedWindow:
+			result.Remaining = rule.Limit - int(state.tokens)
+			result.Reset = state.expires.Sub(now)
+		default:
+			rate := float64(rule.Limit) / rule.Window.Seconds()
+			result.Remaining = int(state.tokens)
+			if state.tokens >= 1 {
+				result.Reset = time.Duration((float64(rule.Limit) - state.tokens) / rate * float64(time.Second))
+			} else {
+				result.Reset = time.Duration((1 - state.tokens) / rate * float64(time.Second))
+			}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// refresh 获取计数并按当前时间补充令牌或切换窗口
+func (m *MemoryRateLimitStore) refresh(key string, rule RateLimitRule, now time.Time) *rateLimitState {
+	state := m.states[key]
+	switch rule.Algorithm {
+	case RateLimitFixedWindow:
+		start := now.Truncate(rule.Window)
+		if state == nil || !state.last.Equal(start) {
+			state = &rateLimitState{last: start}
+			m.states[key] = state
+		}
+		state.expires = start.Add(rule.Window)
+	default:
+		if state == nil {
+			state = &rateLimitState{tokens: float64(rule.Limit), last: now}
+			m.states[key] = state
+		}
+		rate := float64(rule.Limit) / rule.Window.Seconds()
+		state.tokens = math.Min(float64(rule.Limit), state.tokens+now.Sub(state.last).Seconds()*rate)
+		state.last = now
+		state.expires = now.Add(rule.Window)
+	}
+	return state
+}
+
+// available 是否仍有配额
+func (s *rateLimitState) available(rule RateLimitRule) bool {
+	if rule.Algorithm == RateLimitFixedWindow {
+		return s.tokens < float64(rule.Limit)
+	}
+	return s.tokens >= 1
+}
+
+// take 消耗一次配额
+func (s *rateLimitState) take(rule RateLimitRule) {
+	if rule.Algorithm == RateLimitFixedWindow {
+		s.tokens++
+	} else {
+		s.tokens--
+	}
+}
+
+// sweep 清理已过期的计数 每分钟最多执行一次
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, state := range m.states {
+		if now.After(state.expires) {
+			delete(m.states, key)
+		}
+	}
+}
+
+// RateLimiter 限流器 同一请求需同时满足全部规则
+type RateLimiter[ID IDType] struct {
+	store RateLimitStore
+	rules []RateLimitRule
+}
+
+// NewRateLimiter 创建限流器 store为空时使用基于内存的存储
+func NewRateLimiter[ID IDType](store RateLimitStore, rules ...RateLimitRule) *RateLimiter[ID] {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	for _, rule := range rules {
+		if rule.Limit <= 0 || rule.Window <= 0 {
+			panic(fmt.Errorf("rate limit rule requires positive limit and window: %s", rule.Name))
+		}
+	}
+	return &RateLimiter[ID]{store: store, rules: rules}
+}
+
+// rateLimitKey 获取规则的计数key
+func rateLimitKey[ID IDType](request *ginstarter.Request, rule RateLimitRule, endpoint string, authority Authority[ID]) string {
+	parts := []string{rule.Name}
+	if rule.ByEndpoint {
+		parts = append(parts, endpoint)
+	}
+	if rule.ByPlatform {
+		var platform Platform
+		if authority != nil {
+			platform = authority.GetPlatform()
+		}
+		parts = append(parts, string(platform))
+	}
+	if rule.ByIdentity {
+		if authority != nil {
+			parts = append(parts, "id:"+fmt.Sprint(authority.GetIdentityID()))
+		} else {
+			parts = append(parts, "ip:"+request.RequestIP())
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// check 检查请求是否超出限流 超出时返回拒绝的响应 未超出时返回剩余配额最少的结果用于设置限流头
+func (l *RateLimiter[ID]) check(request *ginstarter.Request, endpoint string, fetch AuthorityFetch[ID]) (*RateLimitResult, ginstarter.Response) {
+	var authority Authority[ID]
+	if fetch != nil {
+		authority = fetchAuthority(request, fetch)
+	}
+	var keys []string
+	var rules []RateLimitRule
+	for _, rule := range l.rules {
+		if len(rule.Platforms) > 0 && (authority == nil || !coll.SliceContains(rule.Platforms, authority.GetPlatform())) {
+			continue
+		}
+		keys = append(keys, rateLimitKey(request, rule, endpoint, authority))
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	results, err := l.store.Take(keys, rules, time.Now())
+	if err != nil {
+		// 存储不可用时不限流
+		logger.Logrus().Errorln("rate limit store error:", endpoint, err)
+		return nil, nil
+	}
+	var reported *RateLimitResult
+	for i := range results {
+		result := &results[i]
+		if !result.Allowed {
+			// 超出限流时报告实际耗尽配额的规则
+			if reported == nil || reported.Allowed || result.Remaining < reported.Remaining {
+				reported = result
+			}
+			continue
+		}
+		if reported == nil || result.Remaining < reported.Remaining {
+			reported = result
+		}
+	}
+	if reported.Allowed {
+		return reported, nil
+	}
+	logger.Logrus().Warningln("rate limit exceeded, endpoint:", endpoint, "ip:", request.RequestIP())
+	response := ginstarter.RespRestStatusError(ginstarter.StatusCodeExceededLimit)
+	addRateLimitHeaders(response, reported)
+	response.Data().AddHeader(headerRetryAfter, strconv.FormatInt(int64(math.Ceil(reported.Reset.Seconds())), 10))
+	return reported, response
+}
+
+// handle 在限流下执行Handler 并为响应设置限流头
+func (l *RateLimiter[ID]) handle(request *ginstarter.Request, endpoint string, fetch AuthorityFetch[ID], handler ginstarter.HandlerWrapper) (ginstarter.Response, error) {
+	result, rejected := l.check(request, endpoint, fetch)
+	if rejected != nil {
+		return rejected, nil
+	}
+	response, err := handler(request)
+	if result != nil && response != nil {
+		addRateLimitHeaders(response, result)
+	}
+	return response, err
+}
+
+// addRateLimitHeaders 为响应设置限流头
+func addRateLimitHeaders(response ginstarter.Response, result *RateLimitResult) {
+	response.Data().
+		AddHeader(HeaderRateLimitLimit, strconv.Itoa(result.Limit)).
+		AddHeader(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining)).
+		AddHeader(HeaderRateLimitReset, strconv.FormatInt(int64(math.Ceil(result.Reset.Seconds())), 10))
+}
+
+// SetRateLimiter 设置基础操作的限流器 按基础操作区分接口
+func (b *BaseRouter[ID, S, M, Q, D]) SetRateLimiter(limiter *RateLimiter[ID]) *BaseRouter[ID, S, M, Q, D] {
+	b.rateLimiter = limiter
+	return b
+}
+
+// WithRateLimit 为自定义Handler设置限流 endpoint 用于区分接口
+func (b *BaseRouter[ID, S, M, Q, D]) WithRateLimit(handler ginstarter.HandlerWrapper, limiter *RateLimiter[ID], endpoint string) ginstarter.HandlerWrapper {
+	return withRateLimit(handler, limiter, endpoint, b.authorityFetch)
+}
+
+// WithRateLimit 为Handler设置限流 endpoint 用于区分接口
+func (s *SimpleRouter[ID]) WithRateLimit(handler ginstarter.HandlerWrapper, limiter *RateLimiter[ID], endpoint string) ginstarter.HandlerWrapper {
+	return withRateLimit(handler, limiter, endpoint, s.authorityFetch)
+}
+
+func withRateLimit[ID IDType](handler ginstarter.HandlerWrapper, limiter *RateLimiter[ID], endpoint string, fetch AuthorityFetch[ID]) ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+		return limiter.handle(request, endpoint, fetch, handler)
+	}
+}
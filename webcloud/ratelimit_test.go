@@ -0,0 +1,56 @@
+package webcloud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitRejectDoesNotConsume(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+	loose := RateLimitRule{Name: "loose", Limit: 10, Window: time.Minute}
+	strict := RateLimitRule{Name: "strict", Algorithm: RateLimitFixedWindow, Limit: 1, Window: time.Minute}
+	keys := []string{"loose", "strict"}
+	rules := []RateLimitRule{loose, strict}
+
+	results, _ := store.Take(keys, rules, now)
+	if !results[0].Allowed || !results[1].Allowed {
+		t.Fatalf("first request should be allowed: %+v", results)
+	}
+	for i := 0; i < 3; i++ {
+		results, _ = store.Take(keys, rules, now)
+		if results[0].Allowed || results[1].Allowed {
+			t.Fatalf("request over strict limit should be rejected: %+v", results)
+		}
+	}
+	if results[0].Remaining != 9 {
+		t.Fatalf("rejected requests consumed loose tokens, remaining %d", results[0].Remaining)
+	}
+	if results[1].Remaining != 0 {
+		t.Fatalf("strict remaining %d, want 0", results[1].Remaining)
+	}
+
+	results, _ = store.Take(keys[:1], rules[:1], now)
+	if !results[0].Allowed || results[0].Remaining != 8 {
+		t.Fatalf("loose rule alone should still allow: %+v", results[0])
+	}
+}
+
+func TestRateLimitTokenBucketRefill(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	now := time.Now()
+	rules := []RateLimitRule{{Name: "bucket", Limit: 2, Window: 2 * time.Second}}
+	keys := []string{"bucket"}
+	for i := 0; i < 2; i++ {
+		if results, _ := store.Take(keys, rules, now); !results[0].Allowed {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	results, _ := store.Take(keys, rules, now)
+	if results[0].Allowed || results[0].Reset != time.Second {
+		t.Fatalf("empty bucket should reject with 1s reset: %+v", results[0])
+	}
+	if results, _ = store.Take(keys, rules, now.Add(time.Second)); !results[0].Allowed {
+		t.Fatal("refilled token should be allowed")
+	}
+}
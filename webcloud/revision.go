@@ -0,0 +1,341 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/math/conversion"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/str"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const AuditActionRollback = "rollback" // 回滚数据
+
+// Revision 数据修订版本
+type Revision struct {
+	Rev        int            `json:"rev"` // 版本号 由存储分配 从1开始递增
+	ResourceID string         `json:"resourceId"`
+	Action     string         `json:"action"`   // 产生该版本的动作 save/modify/remove/rollback
+	Snapshot   map[string]any `json:"snapshot"` // 该版本的数据 key为json字段名 删除时为nil
+	IdentityID any            `json:"identityId"`
+	Time       time.Time      `json:"time"`
+}
+
+// FieldChange 字段变更
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// RevisionDetail 修订版本及其相对上一版本的变更
+type RevisionDetail struct {
+	*Revision
+	Changes []FieldChange `json:"changes"`
+}
+
+// RevisionStore 修订版本存储
+type RevisionStore interface {
+	// Append 追加修订版本 由存储分配版本号
+	Append(resource, resourceID string, revision *Revision) error
+	// List 按版本号升序获取全部修订版本
+	List(resource, resourceID string) ([]*Revision, error)
+	// Get 获取指定修订版本 不存在时返回nil
+	Get(resource, resourceID string, rev int) (*Revision, error)
+}
+
+// MemoryRevisionStore 基于内存的修订版本存储
+type MemoryRevisionStore struct {
+	mutex     sync.RWMutex
+	revisions map[string][]*Revision
+}
+
+// NewMemoryRevisionStore 创建基于内存的修订版本存储
+func NewMemoryRevisionStore() *MemoryRevisionStore {
+	return &MemoryRevisionStore{revisions: make(map[string][]*Revision)}
+}
+
+func (m *MemoryRevisionStore) Append(resource, resourceID string, revision *Revision) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := resource + ":" + resourceID
+	revision.Rev = len(m.revisions[key]) + 1
+	m.revisions[key] = append(m.revisions[key], revision)
+	return nil
+}
+
+func (m *MemoryRevisionStore) List(resource, resourceID string) ([]*Revision, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return append([]*Revision(nil), m.revisions[resource+":"+resourceID]...), nil
+}
+
+func (m *MemoryRevisionStore) Get(resource, resourceID string, rev int) (*Revision, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	revisions := m.revisions[resource+":"+resourceID]
+	if rev < 1 || rev > len(revisions) {
+		return nil, nil
+	}
+	return revisions[rev-1], nil
+}
+
+// DiffSnapshots 比较两个数据快照的字段变更 按字段名排序
+func DiffSnapshots(before, after map[string]any) []FieldChange {
+	fields := coll.SliceUnion(coll.MapKeyToSlice(before), coll.MapKeyToSlice(after))
+	sort.Strings(fields)
+	var changes []FieldChange
+	for _, field := range fields {
+		b, a := before[field], after[field]
+		if !reflect.DeepEqual(b, a) {
+			changes = append(changes, FieldChange{Field: field, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// EnableRevisions 启用修订版本 经由基础路由的保存、修改、删除均会记录数据快照
+// 并注册 revisions/:id revisions/:id/:rev rollback/:id/:rev 路由 访问控制与基础操作一致
+// 回滚需同时满足 OperationRollback 与 OperationModifyByID 的访问控制
+func (b *BaseRouter[ID, S, M, Q, D]) EnableRevisions(store RevisionStore) *BaseRouter[ID, S, M, Q, D] {
+	b.revisionStore = store
+	return b
+}
+
+// recordRevision 记录修订版本
+func (b *BaseRouter[ID, S, M, Q, D]) recordRevision(request *ginstarter.Request, action string, id ID, snapshot map[string]any) {
+	if b.revisionStore == nil {
+		return
+	}
+	revision := &Revision{
+		ResourceID: fmt.Sprint(id),
+		Action:     action,
+		Snapshot:   snapshot,
+		Time:       time.Now(),
+	}
+	if b.authorityFetch != nil {
+		if authority := b.GetAuthorityData(request, true); authority != nil {
+			revision.IdentityID = authority.GetIdentityID()
+		}
+	}
+	if err := b.revisionStore.Append(b.resource, revision.ResourceID, revision); err != nil {
+		logger.Logrus().Errorln("append revision error:", b.resource, revision.ResourceID, err)
+	}
+}
+
+// revisionAccessible 检查当前请求是否可访问该数据的修订版本
+// 数据存在时按基础操作的访问控制检查 已删除时使用最近的快照检查数据权限控制字段
+func (b *BaseRouter[ID, S, M, Q, D]) revisionAccessible(request *ginstarter.Request, operation Operation, id ID) (map[string]any, ginstarter.Response) {
+	param := map[string]any{"id": id}
+	if response := b.limitCondition(request, param); response != nil {
+		return nil, response
+	}
+	if !b.applyPolicies(request, operation, param, param) {
+		return nil, ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden)
+	}
+	if b.snapshot(request, param) != nil {
+		return param, nil
+	}
+	revisions, err := b.revisionStore.List(b.resource, fmt.Sprint(id))
+	if err != nil {
+		logger.Logrus().Errorln("list revision error:", err)
+		return nil, ginstarter.RespRestException()
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		snapshot := revisions[i].Snapshot
+		if snapshot == nil {
+			continue
+		}
+		columns := coll.MapCollect(snapshot, func(k string, v any) (string, any) {
+			return str.CamelToSnake(k), v
+		})
+		for column, value := range param {
+			if _, ok := intersectCondition(columns[column], value); column != "id" && !ok {
+				return nil, ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden)
+			}
+		}
+		return param, nil
+	}
+	return nil, ginstarter.RespRestStatusError(ginstarter.StatusCodeNotFound)
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) registerRevisionHandler(router *ginstarter.RouterWrapper) {
+	// 查询数据的全部修订版本
+	router.GET("revisions/:id", b.Revisions())
+	// 查询数据的指定修订版本及其变更
+	router.GET("revisions/:id/:rev", b.Revision())
+	// 将数据回滚至指定修订版本
+	router.POST("rollback/:id/:rev", b.Rollback())
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Revisions() ginstarter.HandlerWrapper {
+	return b.wrap(OperationRevisions, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		id, err := CovertStringToID[ID](request.GetPathParam("id"))
+		if err != nil {
+			return nil, err
+		}
+		if _, response := b.revisionAccessible(request, OperationRevisions, id); response != nil {
+			return response, nil
+		}
+		revisions, err := b.revisionStore.List(b.resource, fmt.Sprint(id))
+		if err != nil {
+			return nil, err
+		}
+		fields, idFields := b.activeMaskFields(request), b.activeIDFields()
+		for i, revision := range revisions {
+			revisions[i] = maskRevision(revision, fields, idFields)
+		}
+		return ginstarter.RespRestSuccess(revisions), nil
+	})
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Revision() ginstarter.HandlerWrapper {
+	return b.wrap(OperationRevisions, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		id, err := CovertStringToID[ID](request.GetPathParam("id"))
+		if err != nil {
+			return nil, err
+		}
+		rev, err := conversion.ParseIntError(request.GetPathParam("rev"))
+		if err != nil {
+			return ginstarter.RespRestBadParameters("bad revision"), nil
+		}
+		if _, response := b.revisionAccessible(request, OperationRevisions, id); response != nil {
+			return response, nil
+		}
+		revision, err := b.revisionStore.Get(b.resource, fmt.Sprint(id), rev)
+		if err != nil {
+			return nil, err
+		}
+		if revision == nil {
+			return ginstarter.RespRestSuccess(), nil
+		}
+		fields, idFields := b.activeMaskFields(request), b.activeIDFields()
+		var previous map[string]any
+		if rev > 1 {
+			previousRevision, err := b.revisionStore.Get(b.resource, fmt.Sprint(id), rev-1)
+			if err != nil {
+				return nil, err
+			}
+			if previousRevision != nil {
+				previous = maskRevision(previousRevision, nil, idFields).Snapshot
+			}
+		}
+		changes := DiffSnapshots(previous, maskRevision(revision, nil, idFields).Snapshot)
+		for i, change := range changes {
+			for _, field := range fields {
+				if field.jsonName == change.Field {
+					changes[i].Before, changes[i].After = maskValue(change.Before, field.kind), maskValue(change.After, field.kind)
+				}
+			}
+		}
+		return ginstarter.RespRestSuccess(RevisionDetail{
+			Revision: maskRevision(revision, fields, idFields),
+			Changes:  changes,
+		}), nil
+	})
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Rollback() ginstarter.HandlerWrapper {
+	return b.wrap(OperationRollback, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		// 回滚即按主键修改数据 同时需满足 OperationModifyByID 的访问控制
+		if response := b.checkOperation(request, OperationModifyByID); response != nil {
+			return response, nil
+		}
+		id, err := CovertStringToID[ID](request.GetPathParam("id"))
+		if err != nil {
+			return nil, err
+		}
+		rev, err := conversion.ParseIntError(request.GetPathParam("rev"))
+		if err != nil {
+			return ginstarter.RespRestBadParameters("bad revision"), nil
+		}
+		param := map[string]any{"id": id}
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		revision, err := b.revisionStore.Get(b.resource, fmt.Sprint(id), rev)
+		if err != nil {
+			return nil, err
+		}
+		if revision == nil || revision.Snapshot == nil {
+			return ginstarter.RespRestBadParameters("revision not restorable"), nil
+		}
+		// 仅回滚允许自由更新的字段 更新内容与修改数据一致以json字段名为key
+		allowed := b.allowedColumns(request, ModeModify)
+		update := coll.MapFilterCollect(revision.Snapshot, func(k string, v any) (string, any, bool) {
+			column := str.CamelToSnake(k)
+			if !coll.SliceContains(allowed, column) || column == b.tenantColumn || column == b.stateColumn() {
+				return "", nil, false
+			}
+			return k, restoreSnapshotValue(v), true
+		})
+		if len(update) == 0 {
+			return ginstarter.RespRestBadParameters("nothing to rollback"), nil
+		}
+		params := coll.MapCollect(update, func(k string, v any) (string, any) {
+			return str.CamelToSnake(k), v
+		})
+		if !b.applyPolicies(request, OperationRollback, params, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		before := b.snapshot(request, param)
+		if before == nil {
+			return ginstarter.RespRestBadParameters("record not found"), nil
+		}
+		row, err := b.bizService.BaseModifyByIDContext(b.requestContext(request), update, param)
+		after := b.snapshot(request, param)
+		b.auditChange(request, AuditActionRollback, id, coll.MapKeyToSlice(params), before, after, row, err)
+		if err != nil {
+			return nil, err
+		}
+		if row > 0 {
+			b.recordRevision(request, AuditActionRollback, id, after)
+		}
+		return ginstarter.RespRestSuccess(), nil
+	})
+}
+
+// maskRevision 对修订版本中的快照脱敏并转换以字符串输出的主键 不修改存储中的数据
+func maskRevision(revision *Revision, fields []maskField, idFields [][]string) *Revision {
+	if (len(fields) == 0 && len(idFields) == 0) || revision.Snapshot == nil {
+		return revision
+	}
+	masked := *revision
+	masked.Snapshot, _ = convertRecord(revision.Snapshot, fields, idFields).(map[string]any)
+	return &masked
+}
+
+// maskValue 按脱敏方式处理单个值
+func maskValue(value any, kind MaskKind) any {
+	if kind == MaskDrop {
+		return nil
+	}
+	if text, ok := value.(string); ok {
+		return MaskString(text, kind)
+	}
+	return value
+}
+
+// restoreSnapshotValue 将快照中的json数值转换为基本数值类型
+func restoreSnapshotValue(value any) any {
+	number, ok := value.(sdkjson.Number)
+	if !ok {
+		return value
+	}
+	if v, err := number.Int64(); err == nil {
+		return v
+	}
+	if v, err := conversion.ParseUint64Error(number.String()); err == nil {
+		return v
+	}
+	if v, err := number.Float64(); err == nil {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,76 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]any{"id": sdkjson.Number("1"), "name": "a", "phone": "13800000000", "tags": []any{"x"}}
+	after := map[string]any{"id": sdkjson.Number("1"), "name": "b", "email": "a@b.c", "tags": []any{"x"}}
+	expected := []FieldChange{
+		{Field: "email", Before: nil, After: "a@b.c"},
+		{Field: "name", Before: "a", After: "b"},
+		{Field: "phone", Before: "13800000000", After: nil},
+	}
+	if changes := DiffSnapshots(before, after); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("DiffSnapshots = %+v, want %+v", changes, expected)
+	}
+	if changes := DiffSnapshots(nil, map[string]any{"name": "a"}); len(changes) != 1 || changes[0].Before != nil {
+		t.Fatalf("diff from nil snapshot: %+v", changes)
+	}
+	if changes := DiffSnapshots(before, before); len(changes) != 0 {
+		t.Fatalf("identical snapshots should not change: %+v", changes)
+	}
+}
+
+func TestMaskRevisionOnRead(t *testing.T) {
+	stored := &Revision{Rev: 1, Snapshot: map[string]any{"id": sdkjson.Number("9007199254740993"), "phone": "13812345678", "note": "n"}}
+	masked := maskRevision(stored, parseMaskFields(reflect.TypeFor[testMaskedRecord]()), [][]string{{"id"}})
+	if masked.Snapshot["phone"] != "138****5678" {
+		t.Fatalf("phone not masked: %v", masked.Snapshot)
+	}
+	if _, ok := masked.Snapshot["note"]; ok {
+		t.Fatalf("dropped field returned: %v", masked.Snapshot)
+	}
+	if masked.Snapshot["id"] != "9007199254740993" {
+		t.Fatalf("id not rendered as string: %v", masked.Snapshot)
+	}
+	if stored.Snapshot["phone"] != "13812345678" || stored.Snapshot["note"] != "n" {
+		t.Fatalf("stored revision modified: %v", stored.Snapshot)
+	}
+	if maskRevision(stored, nil, nil) != stored {
+		t.Fatal("revision without mask fields should be returned as is")
+	}
+}
+
+func TestRollbackFiltersFields(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1, UserID: 5, TenantID: 7, Name: "new"})
+	store := NewMemoryRevisionStore()
+	fetch := func(*ginstarter.Request) Authority[int64] { return &testAuthority{id: 5, tenantID: int64(7)} }
+	router := NewBaseRouterWithAuthority[int64, testRecord, testRecord, testRecord, testRecord](service, fetch, "UserID").
+		SetTenantLimit("TenantID").
+		EnableRevisions(store)
+	_ = store.Append(router.resource, "1", &Revision{Action: AuditActionSave, Snapshot: map[string]any{
+		"id": sdkjson.Number("2"), "userId": sdkjson.Number("5"), "tenantId": sdkjson.Number("8"), "name": "old",
+	}})
+	result := serve(t, router.Rollback(), testCall{params: map[string]string{"id": "1", "rev": "1"}})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("rollback failed: %d %s", result.status, result.message)
+	}
+	call := service.lastCall(t, "ModifyByID")
+	if len(call.update) != 2 || call.update["name"] != "old" || fmt.Sprint(call.update["userId"]) != "5" {
+		t.Fatalf("rollback update should only contain modifiable fields keyed by json name: %v", call.update)
+	}
+	if fmt.Sprint(call.condition["tenant_id"]) != "7" || fmt.Sprint(call.condition["id"]) != "1" {
+		t.Fatalf("unexpected rollback condition: %v", call.condition)
+	}
+	if revisions, _ := store.List(router.resource, "1"); len(revisions) != 2 || revisions[1].Action != AuditActionRollback ||
+		revisions[1].Snapshot["name"] != "old" {
+		t.Fatalf("rollback revision not recorded: %+v", revisions)
+	}
+}
@@ -2,6 +2,9 @@ package webcloud
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	goreflect "reflect"
 
 	"github.com/acexy/golang-toolkit/logger"
 	"github.com/acexy/golang-toolkit/util/coll"
@@ -10,17 +13,39 @@ import (
 	"github.com/acexy/golang-toolkit/util/str"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-acexy/starter-gin/ginstarter"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type mode int8
+// Mode 基础操作的读写模式
+type Mode int8
 
 const (
-	query mode = iota
-	modify
-	save
+	ModeQuery Mode = iota
+	ModeModify
+	ModeSave
+	ModeRemove
 )
 
-var ErrUnAuthority = errors.New("unauthorized request")
+func (m Mode) String() string {
+	switch m {
+	case ModeQuery:
+		return "query"
+	case ModeModify:
+		return "modify"
+	case ModeSave:
+		return "save"
+	case ModeRemove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+const ctxKeyOperation = "_webcloud_operation"
+
+var (
+	ErrUnAuthority           = errors.New("unauthorized request")
+	errAuthorityFetchMissing = errors.New("authority fetch not configured")
+)
 
 // 以下常用的字段用于安全设置，在写场景下强制自动忽略
 var defaultForbitColumns = []string{
@@ -35,31 +60,82 @@ var defaultForbitColumns = []string{
 
 type BaseRouter[ID IDType, S, M, Q, D any] struct {
 	baseBizService BaseBizService[ID, S, M, Q, D]
+	bizService     BaseBizServiceContext[ID, S, D] // 基础操作实际使用的业务服务
+	resource       string                          // 资源名称 用于审计等场景
 
 	// 权限控制
 	authorityFetch           AuthorityFetch[ID]
 	authorityValidate        bool
-	authorityDataLimitColumn string // 权限数据控制的数据库字段
+	authorityDataLimitColumn string                     // 权限数据控制的数据库字段
+	operationSchemes         map[Operation][]AuthScheme // 各基础操作可接受的认证方式
+	operationPermissions     map[Operation][]string     // 各基础操作要求的权限
+	operationRoles           map[Operation][]string     // 各基础操作要求的角色(任一)
+	operationPlatforms       map[Operation][]Platform   // 各基础操作允许的平台
+	platformRules            map[Platform]*platformRule // 各平台的字段安全设置与数据权限控制
+	tenantColumn             string                     // 多租户隔离的数据库字段
+	dataScope                *dataScopeConfig           // 数据范围控制
+	policies                 []Policy[ID]               // 行级数据策略
+	policyDefaultDeny        bool                       // 无策略适用时是否拒绝
+	roleAllowlists           map[string]columnAllowlist // 各角色的字段安全设置
+	maskPermission           string                     // 查看敏感字段原文所需的默认权限
+	bypassPermission         string                     // 跳过数据权限控制所需的权限
+
+	auditSink          AuditSink         // 审计事件输出
+	auditRedactColumns []string          // 审计事件数据快照中删除的数据库字段
+	revisionStore      RevisionStore     // 修订版本存储 为空时不记录修订版本
+	shareStore         ShareStore        // 数据共享存储 为空时不启用数据共享
+	stateMachine       *stateMachine[ID] // 状态字段的状态机
+
+	operationLimits map[Operation]*operationLimiter // 基础操作的超时与并发限制
+	readFlight      *flightGroup[D]                 // 主键查询的请求合并 为空时不合并
+	rateLimiter     *RateLimiter[ID]                // 基础操作的限流器
+	metrics         *MetricsRegistry                // 基础操作的指标
+	tracer          trace.Tracer                    // 基础操作的追踪
+	accessLog       *accessLogConfig                // 基础操作的访问日志
+	bodyLimit       BodyLimit                       // 请求体的大小与嵌套深度限制
+	stringIDs       bool                            // 64位整数主键以字符串响应
 
 	// 字段安全设置
 	modifyAllowedColumns []string // 允许自由更新的数据库字段
 	queryAllowedColumns  []string // 允许自由查询的数据库字段
 	saveAllowedColumns   []string // 允许自由保存的数据库字段
+
+	// 数值字段类型 用于按字段类型转换请求中的json数值
+	queryColumnTypes  map[string]goreflect.Type
+	modifyColumnTypes map[string]goreflect.Type
+}
+
+func structName2Column(field string) string {
+	if field == "ID" || field == "Id" {
+		return "id"
+	}
+	return str.CamelToSnake(str.LowFirstChar(field))
 }
 
 func structNames2Columns(structName []string) []string {
-	return coll.SliceCollect(structName, func(field string) string {
-		if field == "ID" || field == "Id" {
-			return "id"
+	return coll.SliceCollect(structName, structName2Column)
+}
+
+// setStructColumns 按数据库字段名为结构体字段赋值 结构体中不存在的字段将被忽略
+func setStructColumns(structPtr any, values map[string]any) error {
+	fieldNames, err := reflect.AllFieldName(structPtr)
+	if err != nil {
+		return err
+	}
+	fieldValues := make(map[string]any, len(values))
+	for _, fieldName := range fieldNames {
+		if v, ok := values[structName2Column(fieldName)]; ok {
+			fieldValues[fieldName] = v
 		}
-		return str.CamelToSnake(str.LowFirstChar(field))
-	})
+	}
+	return reflect.SetFieldValue(structPtr, fieldValues)
 }
 
 // NewBaseRouter 创建基础路由
 // 为解决零值保存/新增/查询的问题，基础Router的默认动作均将请求参数通过转换为map[jsonKey]jsonValue的形式向后传递参数
 // 但这样会增加请求方自由度，出现恶意的请求编辑的jsonKey字段作为数据库直接交互的字段，基础Router根据不同的结构体所具有的字段来限定
 // **不要将不能更新的字段用于设置在结构体中**
+// 业务服务同时实现 BaseBizServiceContext 时 基础路由优先使用携带上下文的方法
 func NewBaseRouter[ID IDType, S, M, Q, D any](baseBizService BaseBizService[ID, S, M, Q, D]) *BaseRouter[ID, S, M, Q, D] {
 	var q Q
 	var m M
@@ -80,10 +156,14 @@ func NewBaseRouter[ID IDType, S, M, Q, D any](baseBizService BaseBizService[ID,
 
 	return &BaseRouter[ID, S, M, Q, D]{
 		baseBizService: baseBizService,
+		bizService:     AdaptBizService(baseBizService),
+		resource:       goreflect.TypeFor[D]().Name(),
 		modifyAllowedColumns: coll.SliceFilter(structNames2Columns(modifyFieldNames), func(field string) bool {
 			return !coll.SliceContains(defaultForbitColumns, field)
 		}),
 		queryAllowedColumns: structNames2Columns(queryFieldNames),
+		queryColumnTypes:    columnTypes(q),
+		modifyColumnTypes:   columnTypes(m),
 		saveAllowedColumns: coll.SliceFilter(structNames2Columns(saveFieldNames), func(field string) bool {
 			return !coll.SliceContains(defaultForbitColumns, field)
 		}),
@@ -104,37 +184,40 @@ func NewBaseRouterWithAuthority[ID IDType, S, M, Q, D any](baseBizService BaseBi
 	return router
 }
 
+// SetResource 设置资源名称 默认为响应结构体D的类型名称
+func (b *BaseRouter[ID, S, M, Q, D]) SetResource(resource string) *BaseRouter[ID, S, M, Q, D] {
+	b.resource = resource
+	return b
+}
+
 // ConvertJsonToMap 将json转换成map
 // 同时检查请求的字段是否允许 注意，key为自动转换成数据库字段名
-func (b *BaseRouter[ID, S, M, Q, D]) ConvertJsonToMap(request *ginstarter.Request, m mode) (map[string]any, error) {
-	var param map[string]any
-	rawBytes, err := request.GetRawBodyData()
+func (b *BaseRouter[ID, S, M, Q, D]) ConvertJsonToMap(request *ginstarter.Request, m Mode) (map[string]any, error) {
+	param, err := b.readJsonObject(request)
 	if err != nil {
 		return nil, err
 	}
-	json.ParseBytesPanic(rawBytes, &param)
 	if len(param) == 0 {
 		return nil, errors.New("bad request param")
 	}
-	if !b.checkField(param, m) {
+	if !b.checkField(request, param, m) {
 		return nil, errors.New("bad request param")
 	}
+	if err = b.convertNumbers(param, m); err != nil {
+		return nil, err
+	}
 	return coll.MapCollect(param, func(k string, v any) (string, any) {
 		return str.CamelToSnake(k), v
 	}), nil
 }
 
 // checkField 安全检查
-func (b *BaseRouter[ID, S, M, Q, D]) checkField(param map[string]any, m mode) bool {
-	var mathRule []string
-	switch m {
-	case save:
-		mathRule = b.saveAllowedColumns
-	case modify:
-		mathRule = b.modifyAllowedColumns
-	case query:
-		mathRule = b.queryAllowedColumns
-	}
+func (b *BaseRouter[ID, S, M, Q, D]) checkField(request *ginstarter.Request, param map[string]any, m Mode) bool {
+	return b.checkColumns(b.allowedColumns(request, m), param, m)
+}
+
+// checkColumns 检查请求字段均在允许自由操作的数据库字段中 且不包含租户与状态字段
+func (b *BaseRouter[ID, S, M, Q, D]) checkColumns(mathRule []string, param map[string]any, m Mode) bool {
 	input := coll.MapFilterToSlice(param, func(k string, v any) (string, bool) {
 		return str.CamelToSnake(k), true
 	})
@@ -142,38 +225,334 @@ func (b *BaseRouter[ID, S, M, Q, D]) checkField(param map[string]any, m mode) bo
 		logger.Logrus().Warningln("some request field not allowed, all request field : ", input)
 		return false
 	}
+	if b.tenantColumn != "" && coll.SliceContains(input, b.tenantColumn) {
+		logger.Logrus().Warningln("tenant field not allowed in request, all request field : ", input)
+		return false
+	}
+	if m != ModeQuery && b.stateColumn() != "" && coll.SliceContains(input, b.stateColumn()) {
+		logger.Logrus().Warningln("state field only changes by transition, all request field : ", input)
+		return false
+	}
 	return true
 }
 
+// allowedColumns 获取当前请求允许自由操作的数据库字段
+// 优先级: 认证信息所拥有角色的设置 > 请求所属平台的规则 > 路由默认设置
+func (b *BaseRouter[ID, S, M, Q, D]) allowedColumns(request *ginstarter.Request, m Mode) []string {
+	if columns, ok := b.roleAllowedColumns(request, m); ok {
+		return columns
+	}
+	if rule := b.getPlatformRule(request); rule != nil {
+		if columns := rule.columns(m); len(columns) > 0 {
+			return columns
+		}
+	}
+	switch m {
+	case ModeSave:
+		return b.saveAllowedColumns
+	case ModeModify:
+		return b.modifyAllowedColumns
+	case ModeQuery:
+		return b.queryAllowedColumns
+	}
+	return nil
+}
+
+// dataLimitColumn 获取当前请求的数据权限控制字段 请求所属平台不进行数据权限控制时返回false
+func (b *BaseRouter[ID, S, M, Q, D]) dataLimitColumn(request *ginstarter.Request) (string, bool) {
+	if rule := b.getPlatformRule(request); rule != nil {
+		if rule.disableDataLimit {
+			return "", false
+		}
+		if rule.dataLimitColumn != "" {
+			return rule.dataLimitColumn, true
+		}
+	}
+	return b.authorityDataLimitColumn, true
+}
+
+// authorityLimitValues 获取当前请求需强制设置的数据权限控制字段及其值 key为数据库字段名
+// save 为true时用于保存数据 否则用于查询、修改、删除的条件
+// 认证信息不满足路由的数据权限控制要求时返回false
+// 跳过数据权限控制时仅不再限制数据权限控制字段与数据范围 租户隔离始终生效
+func (b *BaseRouter[ID, S, M, Q, D]) authorityLimitValues(request *ginstarter.Request, authority Authority[ID], save bool) (map[string]any, bool) {
+	column, limit := b.dataLimitColumn(request)
+	if limit && b.bypassDataLimit(request, authority) {
+		limit = false
+	}
+	return b.limitValues(authority, column, limit, save)
+}
+
+// limitValues 按数据权限控制字段、数据范围与租户生成需强制设置的字段 limit为false时不限制所有者与数据范围
+func (b *BaseRouter[ID, S, M, Q, D]) limitValues(authority Authority[ID], column string, limit, save bool) (map[string]any, bool) {
+	values := make(map[string]any)
+	if limit {
+		if !b.applyDataScope(authority, column, save, values) {
+			return nil, false
+		}
+	}
+	if b.tenantColumn != "" {
+		tenantID, ok := GetTenantID(authority)
+		if !ok {
+			logger.Logrus().Warningln("tenant required but authority has no tenant, identity:", authority.GetIdentityID())
+			return nil, false
+		}
+		values[b.tenantColumn] = tenantID
+	}
+	return values, true
+}
+
 // SetAuthorityLimitStruct 针对于需要数据权限控制的路由，设置数据权限控制字段
+// paramPtr 需为结构体指针
 func (b *BaseRouter[ID, S, M, Q, D]) SetAuthorityLimitStruct(request *ginstarter.Request, paramPtr any) (bool, error) {
-	if b.authorityValidate {
+	_, pass, err := b.limitStruct(request, paramPtr)
+	return pass, err
+}
+
+// limitStruct 设置数据权限控制字段 并返回已设置的字段及其值 key为数据库字段名
+func (b *BaseRouter[ID, S, M, Q, D]) limitStruct(request *ginstarter.Request, paramPtr any) (map[string]any, bool, error) {
+	values := make(map[string]any)
+	if !b.authorityValidate {
+		return values, true, nil
+	}
+	authority := b.GetAuthorityData(request)
+	if authority == nil {
+		return nil, false, nil
+	}
+	values, pass := b.authorityLimitValues(request, authority, true)
+	if !pass {
+		return nil, false, nil
+	}
+	if err := setStructColumns(paramPtr, values); err != nil {
+		logger.Logrus().Errorln("set authority field error:", err)
+		return nil, false, err
+	}
+	return values, true, nil
+}
+
+// SetAuthorityLimitMap 针对于需要数据权限控制的路由，设置数据权限控制字段
+// 需传入数据库字段名 数据权限控制条件与已存在的条件取交集 按主键操作的数据不在数据权限范围内时返回false
+func (b *BaseRouter[ID, S, M, Q, D]) SetAuthorityLimitMap(request *ginstarter.Request, param map[string]any) bool {
+	return b.limitCondition(request, param) == nil
+}
+
+// limitCondition 设置数据权限控制条件 不允许时返回拒绝的响应
+// 数据权限控制条件不覆盖已存在的条件 而是与其取交集
+// 按主键操作时 主键不在数据权限范围内响应404 条件查询时交集为空则查询不到数据
+func (b *BaseRouter[ID, S, M, Q, D]) limitCondition(request *ginstarter.Request, param map[string]any) ginstarter.Response {
+	if !b.authorityValidate {
+		return nil
+	}
+	authority := b.GetAuthorityData(request)
+	if authority == nil {
+		return ginstarter.RespRestUnAuthorized()
+	}
+	values, pass := b.authorityLimitValues(request, authority, false)
+	if !pass {
+		return ginstarter.RespRestUnAuthorized()
+	}
+	b.applySharing(request, authority, values, param)
+	if !mergeConditions(param, values) {
+		if operation, _ := GetOperation(request); byIDOperation(operation) {
+			logger.Logrus().Warningln("operation", operation, "out of data limit, identity:", authority.GetIdentityID())
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeNotFound)
+		}
+	}
+	return nil
+}
+
+// byIDOperation 是否为按主键操作单条数据的基础操作
+func byIDOperation(operation Operation) bool {
+	switch operation {
+	case OperationSave, OperationQueryOne, OperationQuery, OperationQueryByPage:
+		return false
+	}
+	return true
+}
+
+// mergeConditions 将限制条件与已存在的条件取交集 存在无法满足的条件时返回false 该条件将被设置为空集合
+func mergeConditions(condition, limits map[string]any) bool {
+	satisfiable := true
+	for column, limit := range limits {
+		exist, ok := condition[column]
+		if !ok {
+			condition[column] = limit
+			continue
+		}
+		merged, ok := intersectCondition(exist, limit)
+		if !ok {
+			merged = []any{}
+			satisfiable = false
+		}
+		condition[column] = merged
+	}
+	return satisfiable
+}
+
+// intersectCondition 计算两个条件值的交集 切片表示IN 交集为空时返回false
+func intersectCondition(exist, limit any) (any, bool) {
+	existValues, existMulti := conditionValues(exist)
+	limitValues, limitMulti := conditionValues(limit)
+	switch {
+	case !existMulti && !limitMulti:
+		return exist, conditionEqual(exist, limit)
+	case !existMulti:
+		return exist, coll.SliceAnyContains(limitValues, func(v any) bool { return conditionEqual(v, exist) })
+	case !limitMulti:
+		return limit, coll.SliceAnyContains(existValues, func(v any) bool { return conditionEqual(v, limit) })
+	}
+	merged := coll.SliceFilter(existValues, func(v any) bool {
+		return coll.SliceAnyContains(limitValues, func(l any) bool { return conditionEqual(v, l) })
+	})
+	return merged, len(merged) > 0
+}
+
+// conditionValues 获取多值条件的全部值 非切片时返回false
+func conditionValues(value any) ([]any, bool) {
+	if !isSliceValue(value) {
+		return nil, false
+	}
+	rv := goreflect.ValueOf(value)
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
+
+// conditionEqual 比较条件值 主键等可能以字符串或数值表示 按字面值比较
+func conditionEqual(l, r any) bool {
+	return fmt.Sprint(l) == fmt.Sprint(r)
+}
+
+// AcceptAuthSchemes 限定基础操作可接受的认证方式 未指定操作时作用于全部基础操作
+// 认证方式来自组合认证 NewChainAuthorityFetch 返回的认证信息
+func (b *BaseRouter[ID, S, M, Q, D]) AcceptAuthSchemes(schemes []AuthScheme, operations ...Operation) *BaseRouter[ID, S, M, Q, D] {
+	if len(operations) == 0 {
+		operations = AllOperations
+	}
+	if b.operationSchemes == nil {
+		b.operationSchemes = make(map[Operation][]AuthScheme)
+	}
+	for _, operation := range operations {
+		b.operationSchemes[operation] = schemes
+	}
+	return b
+}
+
+// WithAuthSchemes 为自定义Handler限定可接受的认证方式
+func (b *BaseRouter[ID, S, M, Q, D]) WithAuthSchemes(handler ginstarter.HandlerWrapper, schemes ...AuthScheme) ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
 		authority := b.GetAuthorityData(request)
 		if authority == nil {
-			return false, nil
+			return ginstarter.RespRestUnAuthorized(errAuthorityFetchMissing.Error()), nil
 		}
-		err := reflect.SetFieldValue(paramPtr, map[string]any{
-			b.authorityDataLimitColumn: authority.GetIdentityID(),
-		})
-		if err != nil {
-			logger.Logrus().Errorln("set authority field error:", err)
-			return false, err
+		if !checkAuthScheme(authority, schemes) {
+			return ginstarter.RespRestUnAuthorized("auth scheme not accepted"), nil
 		}
+		return handler(request)
 	}
-	return true, nil
 }
 
-// SetAuthorityLimitMap 针对于需要数据权限控制的路由，设置数据权限控制字段
-// 需传入数据库字段名
-func (b *BaseRouter[ID, S, M, Q, D]) SetAuthorityLimitMap(request *ginstarter.Request, param map[string]any) bool {
-	if b.authorityValidate {
+// RequirePermissions 声明基础操作要求拥有的全部权限 认证信息需实现 PermissionAuthority
+// 缺少权限时响应403
+func (b *BaseRouter[ID, S, M, Q, D]) RequirePermissions(operation Operation, permissions ...string) *BaseRouter[ID, S, M, Q, D] {
+	if b.operationPermissions == nil {
+		b.operationPermissions = make(map[Operation][]string)
+	}
+	b.operationPermissions[operation] = permissions
+	return b
+}
+
+// RequireRoles 声明基础操作要求拥有的角色 拥有任一角色即可 认证信息需实现 PermissionAuthority
+// 缺少角色时响应403
+func (b *BaseRouter[ID, S, M, Q, D]) RequireRoles(operation Operation, roles ...string) *BaseRouter[ID, S, M, Q, D] {
+	if b.operationRoles == nil {
+		b.operationRoles = make(map[Operation][]string)
+	}
+	b.operationRoles[operation] = roles
+	return b
+}
+
+// WithPermissions 为自定义Handler声明要求拥有的全部权限
+func (b *BaseRouter[ID, S, M, Q, D]) WithPermissions(handler ginstarter.HandlerWrapper, permissions ...string) ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
 		authority := b.GetAuthorityData(request)
 		if authority == nil {
-			return false
+			return ginstarter.RespRestUnAuthorized(errAuthorityFetchMissing.Error()), nil
+		}
+		if !HasPermission(authority, permissions...) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
 		}
-		param[str.CamelToSnake(b.authorityDataLimitColumn)] = authority.GetIdentityID()
+		return handler(request)
 	}
-	return true
+}
+
+// GetOperation 获取当前请求正在执行的基础操作 非基础操作返回false
+func GetOperation(request *ginstarter.Request) (Operation, bool) {
+	v, ok := request.GetValue(ctxKeyOperation)
+	if !ok {
+		return "", false
+	}
+	operation, ok := v.(Operation)
+	return operation, ok
+}
+
+// wrap 包装基础操作 执行基础操作前的通用检查
+func (b *BaseRouter[ID, S, M, Q, D]) wrap(operation Operation, handler ginstarter.HandlerWrapper) ginstarter.HandlerWrapper {
+	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+		request.SetValue(ctxKeyOperation, operation)
+		request.SetValue(ctxKeyBodyLimit, b.bodyLimits().MaxBytes)
+		return b.trace(request, operation, func(request *ginstarter.Request) (ginstarter.Response, error) {
+			return b.logAccess(request, operation, func(request *ginstarter.Request) (ginstarter.Response, error) {
+				return b.measure(request, operation, func(request *ginstarter.Request) (ginstarter.Response, error) {
+					// 限流先于权限检查 未认证或无权限的请求同样计入限流
+					if b.rateLimiter != nil {
+						return b.rateLimiter.handle(request, string(operation), b.authorityFetch, func(request *ginstarter.Request) (ginstarter.Response, error) {
+							return b.authorize(request, operation, handler)
+						})
+					}
+					return b.authorize(request, operation, handler)
+				})
+			})
+		})
+	}
+}
+
+// authorize 检查基础操作的权限后在超时与并发限制下执行
+func (b *BaseRouter[ID, S, M, Q, D]) authorize(request *ginstarter.Request, operation Operation, handler ginstarter.HandlerWrapper) (ginstarter.Response, error) {
+	if response := b.checkOperation(request, operation); response != nil {
+		return response, nil
+	}
+	return b.limit(request, operation, handler)
+}
+
+// checkOperation 检查当前请求是否允许执行基础操作 不允许时返回拒绝的响应
+func (b *BaseRouter[ID, S, M, Q, D]) checkOperation(request *ginstarter.Request, operation Operation) ginstarter.Response {
+	schemes := b.operationSchemes[operation]
+	permissions := b.operationPermissions[operation]
+	roles := b.operationRoles[operation]
+	platforms := b.operationPlatforms[operation]
+	if len(schemes) == 0 && len(permissions) == 0 && len(roles) == 0 && len(platforms) == 0 {
+		return nil
+	}
+	authority := b.GetAuthorityData(request)
+	if authority == nil {
+		// 未设置认证方式时无法获取认证信息
+		return ginstarter.RespRestUnAuthorized(errAuthorityFetchMissing.Error())
+	}
+	if !checkAuthScheme(authority, schemes) {
+		return ginstarter.RespRestUnAuthorized("auth scheme not accepted")
+	}
+	if !HasPermission(authority, permissions...) || !HasRole(authority, roles...) {
+		logger.Logrus().Warningln("operation", operation, "forbidden, identity:", authority.GetIdentityID())
+		return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden)
+	}
+	if len(platforms) > 0 && !coll.SliceContains(platforms, authority.GetPlatform()) {
+		logger.Logrus().Warningln("operation", operation, "not allowed for platform:", authority.GetPlatform())
+		return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden)
+	}
+	return nil
 }
 
 // RegisterBaseHandler 注册基础路由
@@ -191,6 +570,15 @@ func (b *BaseRouter[ID, S, M, Q, D]) RegisterBaseHandler(router *ginstarter.Rout
 	router.PUT1("by-id/:id", []string{gin.MIMEJSON}, baseRouter.ModifyById())
 	// 通过主键删除数据
 	router.DELETE("by-id/:id", baseRouter.RemoveById())
+	if baseRouter.revisionStore != nil {
+		baseRouter.registerRevisionHandler(router)
+	}
+	if baseRouter.shareStore != nil {
+		baseRouter.registerShareHandler(router)
+	}
+	if baseRouter.stateMachine != nil {
+		baseRouter.registerTransitionHandler(router)
+	}
 }
 
 // GetAuthorityData 获取当前请求的认证信息
@@ -199,110 +587,159 @@ func (b *BaseRouter[ID, S, M, Q, D]) GetAuthorityData(request *ginstarter.Reques
 		logger.Logrus().Warningln("miss authority fetch method")
 		return nil
 	}
-	result := b.authorityFetch(request)
+	result := fetchAuthority(request, b.authorityFetch)
 	if len(notRequired) > 0 && notRequired[0] {
 		return result
 	}
 	if result == nil {
-		request.Panic(ginstarter.StatusCodeUnauthorized, ErrUnAuthority)
+		AbortRequest(request, ginstarter.StatusCodeUnauthorized, ErrUnAuthority)
 	}
-	return b.authorityFetch(request)
+	return result
 }
 
 // 基础CRUD
 
 func (b *BaseRouter[ID, S, M, Q, D]) Save() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+	return b.wrap(OperationSave, func(request *ginstarter.Request) (ginstarter.Response, error) {
 		var param S
-		request.MustBindBodyAuto(&param)
-		pass, err := b.SetAuthorityLimitStruct(request, param)
+		b.acceptStringIDs(request)
+		object, err := b.readJsonObject(request)
+		if err != nil {
+			return bodyErrorResponse(err), nil
+		}
+		// 绑定前检查请求字段 不允许自由保存的字段响应参数错误
+		if !b.checkField(request, object, ModeSave) {
+			return ginstarter.RespRestBadParameters(), nil
+		}
+		if err = b.bindJson(request, &param); err != nil {
+			return bodyErrorResponse(err), nil
+		}
+		limits, pass, err := b.limitStruct(request, &param)
 		if err != nil {
 			return nil, err
 		}
 		if !pass {
 			return ginstarter.RespRestUnAuthorized(), nil
 		}
-		id, err := b.baseBizService.Save(&param)
+		if initial := b.initialStateValues(); initial != nil {
+			if err = setStructColumns(&param, initial); err != nil {
+				return nil, err
+			}
+			maps.Copy(limits, initial)
+		}
+		if len(b.policies) > 0 {
+			// 策略强制设置的字段不可覆盖已设置的数据权限控制、租户与初始状态字段
+			values := maps.Clone(limits)
+			if !b.applyPolicies(request, OperationSave, structToColumns(&param), values) {
+				return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+			}
+			if err = setStructColumns(&param, values); err != nil {
+				return nil, err
+			}
+		}
+		id, err := b.bizService.SaveContext(b.requestContext(request), &param)
+		if err == nil && b.revisionStore != nil {
+			b.recordRevision(request, AuditActionSave, id, b.snapshot(request, map[string]any{"id": id}))
+		}
+		if b.auditEnabled() {
+			after := structToColumns(&param)
+			if after != nil && err == nil {
+				after["id"] = id
+			}
+			b.auditChange(request, AuditActionSave, id, coll.MapKeyToSlice(after), nil, after, 1, err)
+		}
 		if err != nil {
-			logger.Logrus().Errorln("cant save:", param, err)
+			logger.Logrus().Errorln("cant save:", b.resource, err)
 			return nil, err
 		}
-		return ginstarter.RespRestSuccess(id), nil
-	}
+		return ginstarter.RespRestSuccess(b.renderID(id)), nil
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) QueryById() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+	return b.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
 		id, err := CovertStringToID[ID](request.GetPathParam("id"))
 		if err != nil {
 			return nil, err
 		}
 		param := map[string]any{"id": id}
-		flag := b.SetAuthorityLimitMap(request, param)
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
 		}
-		var d D
-		row, err := b.baseBizService.BaseQueryByID(param, &d)
+		if !b.applyPolicies(request, OperationQueryByID, param, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		d, row, err := b.queryByIDCoalesced(b.requestContext(request), param)
 		if err != nil {
 			return nil, err
 		}
+		b.observeRows(request, int(row))
 		if row > 0 {
-			return ginstarter.RespRestSuccess(d), nil
+			return ginstarter.RespRestSuccess(b.renderRecord(request, &d)), nil
 		}
 		return ginstarter.RespRestSuccess(), nil
-	}
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) Query() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
-		param, err := b.ConvertJsonToMap(request, query)
+	return b.wrap(OperationQuery, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		param, err := b.ConvertJsonToMap(request, ModeQuery)
 		if err != nil {
-			return ginstarter.RespRestBadParameters(), nil
+			return bodyErrorResponse(err), nil
 		}
-		flag := b.SetAuthorityLimitMap(request, param)
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		if !b.applyPolicies(request, OperationQuery, param, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
 		}
 		var ds []*D
-		row, err := b.baseBizService.BaseQuery(param, &ds)
+		row, err := b.bizService.BaseQueryContext(b.requestContext(request), param, &ds)
 		if err != nil {
 			return nil, err
 		}
+		b.observeRows(request, len(ds))
 		if row == 0 {
 			return ginstarter.RespRestSuccess(), nil
 		}
-		return ginstarter.RespRestSuccess(ds), nil
-	}
+		return ginstarter.RespRestSuccess(b.renderRecords(request, ds)), nil
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) QueryOne() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
-		param, err := b.ConvertJsonToMap(request, query)
+	return b.wrap(OperationQueryOne, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		param, err := b.ConvertJsonToMap(request, ModeQuery)
 		if err != nil {
-			return ginstarter.RespRestBadParameters(), nil
+			return bodyErrorResponse(err), nil
 		}
-		flag := b.SetAuthorityLimitMap(request, param)
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		if !b.applyPolicies(request, OperationQueryOne, param, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
 		}
 		var d D
-		row, err := b.baseBizService.BaseQueryOne(param, &d)
+		row, err := b.bizService.BaseQueryOneContext(b.requestContext(request), param, &d)
 		if err != nil {
 			return nil, err
 		}
+		b.observeRows(request, int(row))
 		if row == 0 {
 			return ginstarter.RespRestSuccess(), nil
 		}
-		return ginstarter.RespRestSuccess(d), nil
-	}
+		return ginstarter.RespRestSuccess(b.renderRecord(request, &d)), nil
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) QueryByPage() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
-		rawBytes, err := request.GetRawBodyData()
+	return b.wrap(OperationQueryByPage, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		rawBytes, err := b.readBody(request)
 		if err != nil {
-			return nil, err
+			return bodyErrorResponse(err), nil
+		}
+		body, err := decodeJsonObject(rawBytes, b.bodyLimits().MaxDepth)
+		if err != nil {
+			return bodyErrorResponse(err), nil
 		}
 		paramJson := json.NewGJsonBytes(rawBytes)
 		sizeValue := paramJson.Get("size")
@@ -319,90 +756,121 @@ func (b *BaseRouter[ID, S, M, Q, D]) QueryByPage() ginstarter.HandlerWrapper {
 			Number: int(number),
 			Size:   int(size),
 		}
-		condition := paramJson.Get("condition")
-		rawConditionJson := condition.RawJsonString()
 		param := make(map[string]any)
-		flag := b.SetAuthorityLimitMap(request, param)
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
-		}
-		if rawConditionJson != "" {
-			if err != nil {
-				return nil, err
+		if condition, exist := body["condition"]; exist && condition != nil {
+			var ok bool
+			if param, ok = condition.(map[string]any); !ok {
+				return ginstarter.RespRestBadParameters("condition must be a json object"), nil
 			}
-			json.ParseStringPanic(rawConditionJson, &param)
 			if len(param) == 0 {
 				return ginstarter.RespRestBadParameters(), nil
 			}
-			if !b.checkField(param, query) {
+			if !b.checkField(request, param, ModeQuery) {
 				return ginstarter.RespRestBadParameters(), nil
 			}
+			if err = b.convertNumbers(param, ModeQuery); err != nil {
+				return bodyErrorResponse(err), nil
+			}
 			param = coll.MapCollect(param, func(k string, v any) (string, any) {
 				return str.CamelToSnake(k), v
 			})
 		}
-		err = b.baseBizService.BaseQueryByPager(param, &pager)
+		// 数据权限控制条件与请求条件取交集 请求条件无法放宽数据权限范围
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		if !b.applyPolicies(request, OperationQueryByPage, param, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		err = b.bizService.BaseQueryByPagerContext(b.requestContext(request), param, &pager)
 		if err != nil {
 			return nil, err
 		}
-		return ginstarter.RespRestSuccess(pager), nil
-	}
+		b.observeRows(request, len(pager.Records))
+		return ginstarter.RespRestSuccess(b.renderPager(request, pager)), nil
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) ModifyById() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+	return b.wrap(OperationModifyByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
 		id, err := CovertStringToID[ID](request.GetPathParam("id"))
 		if err != nil {
 			return nil, err
 		}
-		var update map[string]any
-		rawBytes, err := request.GetRawBodyData()
+		update, err := b.readJsonObject(request)
 		if err != nil {
-			return nil, err
+			return bodyErrorResponse(err), nil
 		}
-		json.ParseBytesPanic(rawBytes, &update)
 		if len(update) == 0 {
 			return ginstarter.RespRestBadParameters(), nil
 		}
-		if !b.checkField(update, modify) {
+		if !b.checkField(request, update, ModeModify) {
 			return ginstarter.RespRestBadParameters(), nil
 		}
+		if err = b.convertNumbers(update, ModeModify); err != nil {
+			return bodyErrorResponse(err), nil
+		}
 		param := map[string]any{"id": id}
-		flag := b.SetAuthorityLimitMap(request, param)
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		// 策略参数的key为数据库字段名 更新内容本身仍以请求字段名传递至业务服务
+		params := coll.MapCollect(update, func(k string, v any) (string, any) {
+			return str.CamelToSnake(str.LowFirstChar(k)), v
+		})
+		if !b.applyPolicies(request, OperationModifyByID, params, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		var before, after map[string]any
+		if b.auditEnabled() {
+			before = b.snapshot(request, param)
+		}
+		row, err := b.bizService.BaseModifyByIDContext(b.requestContext(request), update, param)
+		if b.auditEnabled() || (row > 0 && b.revisionStore != nil) {
+			after = b.snapshot(request, param)
+		}
+		if b.auditEnabled() {
+			changed := coll.MapKeyToSlice(update)
+			b.auditChange(request, AuditActionModify, id, structNames2Columns(changed), before, after, row, err)
 		}
-		_, err = b.baseBizService.BaseModifyByID(update, param)
 		if err != nil {
 			return nil, err
 		}
+		if row > 0 {
+			b.recordRevision(request, AuditActionModify, id, after)
+		}
 		return ginstarter.RespRestSuccess(), nil
-	}
+	})
 }
 
 func (b *BaseRouter[ID, S, M, Q, D]) RemoveById() ginstarter.HandlerWrapper {
-	return func(request *ginstarter.Request) (ginstarter.Response, error) {
+	return b.wrap(OperationRemoveByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
 		id, err := CovertStringToID[ID](request.GetPathParam("id"))
 		if err != nil {
 			return nil, err
 		}
 		param := map[string]any{"id": id}
-		flag := b.SetAuthorityLimitMap(request, param)
-		if err != nil {
-			return nil, err
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
 		}
-		if !flag {
-			return ginstarter.RespRestUnAuthorized(), nil
+		if !b.applyPolicies(request, OperationRemoveByID, param, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
 		}
-		row, err := b.baseBizService.BaseRemoveByID(param)
+		var before map[string]any
+		if b.auditEnabled() {
+			before = b.snapshot(request, param)
+		}
+		row, err := b.bizService.BaseRemoveByIDContext(b.requestContext(request), param)
+		b.auditChange(request, AuditActionRemove, id, nil, before, nil, row, err)
 		if err != nil {
 			return nil, err
 		}
 		if row > 0 {
+			b.recordRevision(request, AuditActionRemove, id, nil)
 			return ginstarter.RespRestSuccess(), nil
 		}
 		return ginstarter.RespRestBadParameters(), nil
-	}
+	})
 }
 
 // SimpleRouter 简单路由，不含数据库结构相关的方法
@@ -423,12 +891,12 @@ func (s *SimpleRouter[ID]) GetAuthorityData(request *ginstarter.Request, notRequ
 		logger.Logrus().Warningln("miss authority fetch method")
 		return nil
 	}
-	result := s.authorityFetch(request)
+	result := fetchAuthority(request, s.authorityFetch)
 	if len(notRequired) > 0 && notRequired[0] {
 		return result
 	}
 	if result == nil {
-		request.Panic(ginstarter.StatusCodeUnauthorized, ErrUnAuthority)
+		AbortRequest(request, ginstarter.StatusCodeUnauthorized, ErrUnAuthority)
 	}
 	return result
 }
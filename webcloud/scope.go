@@ -0,0 +1,121 @@
+package webcloud
+
+import (
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/str"
+)
+
+// DataScopeKind 数据范围类型
+type DataScopeKind int8
+
+const (
+	DataScopeSelf       DataScopeKind = iota // 仅本人的数据
+	DataScopeOrgUnit                         // 本组织单元的数据
+	DataScopeOrgSubtree                      // 本组织单元及其全部下级的数据
+	DataScopeCustom                          // 自定义的记录集合
+	DataScopeAll                             // 全部数据
+)
+
+// DataScope 认证信息可访问的数据范围
+type DataScope struct {
+	Kind      DataScopeKind
+	OrgUnitID any   // 所属组织单元 DataScopeOrgUnit/DataScopeOrgSubtree 时必须
+	IDs       []any // 可访问的记录标识 DataScopeCustom 时使用
+}
+
+// DataScopeAuthority 含有数据范围的认证信息 Authority 的可选扩展
+type DataScopeAuthority interface {
+	// GetDataScope 获取可访问的数据范围 返回nil时按数据权限控制字段限制本人数据
+	GetDataScope() *DataScope
+}
+
+// OrgUnitResolver 解析组织单元及其全部下级组织单元 返回结果需包含自身
+type OrgUnitResolver func(orgUnitID any) ([]any, error)
+
+// DataScopeConfig 数据范围配置
+type DataScopeConfig struct {
+	OrgUnitFieldName string          // 记录所属组织单元的字段
+	CustomFieldName  string          // DataScopeCustom 匹配的字段 默认为主键
+	OrgUnitResolver  OrgUnitResolver // 组织单元下级解析 使用 DataScopeOrgSubtree 时必须
+}
+
+type dataScopeConfig struct {
+	orgUnitColumn   string
+	customColumn    string
+	orgUnitResolver OrgUnitResolver
+}
+
+// SetDataScope 启用数据范围控制 认证信息需实现 DataScopeAuthority
+// 数据范围将替代数据权限控制字段转换为查询、修改、删除的条件 多值条件以切片传递给BaseBizService 应按IN处理
+// 保存时仍以数据权限控制字段记录本人 并记录所属组织单元
+func (b *BaseRouter[ID, S, M, Q, D]) SetDataScope(config DataScopeConfig) *BaseRouter[ID, S, M, Q, D] {
+	scope := &dataScopeConfig{
+		customColumn:    "id",
+		orgUnitResolver: config.OrgUnitResolver,
+	}
+	if config.OrgUnitFieldName != "" {
+		scope.orgUnitColumn = str.CamelToSnake(str.LowFirstChar(config.OrgUnitFieldName))
+	}
+	if config.CustomFieldName != "" {
+		scope.customColumn = structName2Column(config.CustomFieldName)
+	}
+	b.dataScope = scope
+	return b
+}
+
+// applyDataScope 将认证信息的数据范围转换为数据权限控制字段 数据范围无法满足时返回false
+func (b *BaseRouter[ID, S, M, Q, D]) applyDataScope(authority Authority[ID], ownerColumn string, save bool, values map[string]any) bool {
+	var scope *DataScope
+	if b.dataScope != nil {
+		if scopeAuthority, ok := AuthorityAs[DataScopeAuthority](authority); ok {
+			scope = scopeAuthority.GetDataScope()
+		}
+	}
+	if scope == nil || save {
+		if ownerColumn != "" {
+			values[ownerColumn] = authority.GetIdentityID()
+		}
+		if scope != nil && scope.OrgUnitID != nil && b.dataScope.orgUnitColumn != "" {
+			values[b.dataScope.orgUnitColumn] = scope.OrgUnitID
+		}
+		return true
+	}
+	switch scope.Kind {
+	case DataScopeSelf:
+		if ownerColumn == "" {
+			logger.Logrus().Warningln("data scope self requires data limit field")
+			return false
+		}
+		values[ownerColumn] = authority.GetIdentityID()
+	case DataScopeOrgUnit, DataScopeOrgSubtree:
+		if b.dataScope.orgUnitColumn == "" || scope.OrgUnitID == nil {
+			logger.Logrus().Warningln("data scope org unit requires org unit field and authority org unit, identity:", authority.GetIdentityID())
+			return false
+		}
+		if scope.Kind == DataScopeOrgUnit {
+			values[b.dataScope.orgUnitColumn] = scope.OrgUnitID
+			break
+		}
+		if b.dataScope.orgUnitResolver == nil {
+			logger.Logrus().Warningln("data scope org subtree requires org unit resolver")
+			return false
+		}
+		orgUnitIDs, err := b.dataScope.orgUnitResolver(scope.OrgUnitID)
+		if err != nil {
+			logger.Logrus().Errorln("resolve org unit error:", scope.OrgUnitID, err)
+			return false
+		}
+		values[b.dataScope.orgUnitColumn] = orgUnitIDs
+	case DataScopeCustom:
+		ids := scope.IDs
+		if ids == nil {
+			ids = []any{}
+		}
+		values[b.dataScope.customColumn] = ids
+	case DataScopeAll:
+	default:
+		logger.Logrus().Warningln("unknown data scope kind:", scope.Kind)
+		return false
+	}
+	return true
+}
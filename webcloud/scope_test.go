@@ -0,0 +1,104 @@
+package webcloud
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRecord struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"userId"`
+	TenantID int64  `json:"tenantId"`
+	Name     string `json:"name"`
+}
+
+type testRouter = BaseRouter[int64, testRecord, testRecord, testRecord, testRecord]
+
+type testAuthority struct {
+	id       int64
+	tenantID any
+	scope    *DataScope
+}
+
+func (a *testAuthority) GetIdentityID() int64     { return a.id }
+func (a *testAuthority) GetPlatform() Platform    { return "test" }
+func (a *testAuthority) GetTenantID() any         { return a.tenantID }
+func (a *testAuthority) GetDataScope() *DataScope { return a.scope }
+
+func customScopeValues(t *testing.T, ids ...any) map[string]any {
+	t.Helper()
+	router := &testRouter{dataScope: &dataScopeConfig{customColumn: "id"}}
+	authority := &testAuthority{id: 1, scope: &DataScope{Kind: DataScopeCustom, IDs: ids}}
+	values := make(map[string]any)
+	if !router.applyDataScope(authority, "user_id", false, values) {
+		t.Fatal("custom data scope rejected")
+	}
+	return values
+}
+
+func TestCustomScopeByIDInScope(t *testing.T) {
+	param := map[string]any{"id": int64(2)}
+	if !mergeConditions(param, customScopeValues(t, int64(1), int64(2))) {
+		t.Fatal("id in custom scope should be accessible")
+	}
+	if param["id"] != int64(2) {
+		t.Fatalf("by-id condition overwritten: %v", param["id"])
+	}
+}
+
+func TestCustomScopeByIDOutOfScope(t *testing.T) {
+	param := map[string]any{"id": int64(3)}
+	if mergeConditions(param, customScopeValues(t, int64(1), int64(2))) {
+		t.Fatal("id out of custom scope should be rejected")
+	}
+	if ids, ok := param["id"].([]any); !ok || len(ids) != 0 {
+		t.Fatalf("out of scope condition should match nothing: %v", param["id"])
+	}
+}
+
+func TestCustomScopeByIDEmptyScope(t *testing.T) {
+	param := map[string]any{"id": int64(1)}
+	if mergeConditions(param, customScopeValues(t)) {
+		t.Fatal("empty custom scope should reject every id")
+	}
+}
+
+func TestCustomScopeByStringID(t *testing.T) {
+	param := map[string]any{"id": int64(2)}
+	if !mergeConditions(param, customScopeValues(t, "1", "2")) {
+		t.Fatal("custom scope ids given as strings should match numeric id")
+	}
+}
+
+func TestCustomScopeListIntersect(t *testing.T) {
+	param := map[string]any{"id": []int64{2, 3, 4}, "name": "a"}
+	if !mergeConditions(param, customScopeValues(t, int64(1), int64(2), int64(3))) {
+		t.Fatal("overlapping list condition should be satisfiable")
+	}
+	if !reflect.DeepEqual(param["id"], []any{int64(2), int64(3)}) {
+		t.Fatalf("list condition not intersected: %v", param["id"])
+	}
+	if param["name"] != "a" {
+		t.Fatalf("unrelated condition changed: %v", param["name"])
+	}
+}
+
+func TestCustomScopeListAbsent(t *testing.T) {
+	param := map[string]any{"name": "a"}
+	if !mergeConditions(param, customScopeValues(t, int64(1))) {
+		t.Fatal("absent condition should be satisfiable")
+	}
+	if !reflect.DeepEqual(param["id"], []any{int64(1)}) {
+		t.Fatalf("custom scope not applied: %v", param["id"])
+	}
+}
+
+func TestMergeConditionsKeepsExistingScalar(t *testing.T) {
+	param := map[string]any{"tenant_id": int64(2)}
+	if mergeConditions(param, map[string]any{"tenant_id": int64(1)}) {
+		t.Fatal("conflicting tenant condition should be unsatisfiable")
+	}
+	if ids, ok := param["tenant_id"].([]any); !ok || len(ids) != 0 {
+		t.Fatalf("conflicting condition should match nothing: %v", param["tenant_id"])
+	}
+}
@@ -0,0 +1,272 @@
+package webcloud
+
+import (
+	"cmp"
+	"context"
+	sdkjson "encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const headerTestHandler = "X-Test-Handler"
+
+var (
+	testEngine    *gin.Engine
+	testHandlers  sync.Map
+	testHandlerID atomic.Int64
+)
+
+// testDispatchRouter 将请求分发至测试注册的Handler 使Handler在ginstarter的完整流程中执行
+type testDispatchRouter struct{}
+
+func (testDispatchRouter) Info() *ginstarter.RouterInfo {
+	return &ginstarter.RouterInfo{GroupPath: "test"}
+}
+
+func (testDispatchRouter) Handlers(router *ginstarter.RouterWrapper) {
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	router.MATCH(methods, "*path", func(request *ginstarter.Request) (ginstarter.Response, error) {
+		v, ok := testHandlers.Load(request.GetHeader(headerTestHandler))
+		if !ok {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeNotFound), nil
+		}
+		route := v.(*testRoute)
+		ctx := request.RawGinContext()
+		ctx.Params = ctx.Params[:0]
+		for key, value := range route.params {
+			ctx.Params = append(ctx.Params, gin.Param{Key: key, Value: value})
+		}
+		return route.handler(request)
+	})
+}
+
+type testRoute struct {
+	handler ginstarter.HandlerWrapper
+	params  map[string]string
+}
+
+func TestMain(m *testing.M) {
+	starter := &ginstarter.GinStarter{Config: ginstarter.GinConfig{
+		ListenAddress: "127.0.0.1:0",
+		Routers:       []ginstarter.Router{testDispatchRouter{}},
+	}}
+	engine, err := starter.Start()
+	if err != nil {
+		fmt.Println("start gin error:", err)
+		os.Exit(1)
+	}
+	testEngine = engine.(*gin.Engine)
+	os.Exit(m.Run())
+}
+
+// testCall 经由ginstarter执行Handler的请求
+type testCall struct {
+	method string
+	path   string
+	params map[string]string // 路径参数
+	header map[string]string
+	body   string
+}
+
+// testResult Handler的响应
+type testResult struct {
+	httpStatus int
+	header     http.Header
+	status     ginstarter.StatusCode
+	message    string
+	data       sdkjson.RawMessage
+}
+
+// serve 经由ginstarter执行Handler并解析Rest响应
+func serve(t *testing.T, handler ginstarter.HandlerWrapper, call testCall) *testResult {
+	t.Helper()
+	id := fmt.Sprint(testHandlerID.Add(1))
+	testHandlers.Store(id, &testRoute{handler: handler, params: call.params})
+	defer testHandlers.Delete(id)
+	if call.method == "" {
+		call.method = http.MethodPost
+	}
+	httpRequest := httptest.NewRequest(call.method, "/test/"+strings.TrimPrefix(call.path, "/"), strings.NewReader(call.body))
+	if call.body != "" {
+		httpRequest.Header.Set("Content-Type", gin.MIMEJSON)
+	}
+	httpRequest.Header.Set(headerTestHandler, id)
+	for key, value := range call.header {
+		httpRequest.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	testEngine.ServeHTTP(recorder, httpRequest)
+	result := &testResult{httpStatus: recorder.Code, header: recorder.Header()}
+	var rest struct {
+		Status *ginstarter.RestRespStatusStruct `json:"status"`
+		Data   sdkjson.RawMessage               `json:"data"`
+	}
+	if err := sdkjson.Unmarshal(recorder.Body.Bytes(), &rest); err == nil && rest.Status != nil {
+		result.status = rest.Status.StatusCode
+		result.message = string(rest.Status.StatusMessage)
+		result.data = rest.Data
+	}
+	return result
+}
+
+// decodeData 解析响应数据
+func (r *testResult) decodeData(t *testing.T, ptr any) {
+	t.Helper()
+	decoder := sdkjson.NewDecoder(strings.NewReader(string(r.data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(ptr); err != nil {
+		t.Fatalf("decode response data %s: %v", r.data, err)
+	}
+}
+
+// memoryBizCall 业务服务的一次调用
+type memoryBizCall struct {
+	method    string
+	ctx       context.Context
+	update    map[string]any
+	condition map[string]any
+}
+
+// memoryBizService 基于内存的业务服务 记录每次调用 条件的键为数据库字段名
+type memoryBizService struct {
+	testBizService
+	mutex   sync.Mutex
+	records map[int64]*testRecord
+	calls   []memoryBizCall
+}
+
+func newMemoryBizService(records ...testRecord) *memoryBizService {
+	service := &memoryBizService{records: make(map[int64]*testRecord)}
+	for i := range records {
+		service.records[records[i].ID] = &records[i]
+	}
+	return service
+}
+
+func (m *memoryBizService) record(method string, ctx context.Context, update, condition map[string]any) {
+	m.calls = append(m.calls, memoryBizCall{method: method, ctx: ctx, update: update, condition: condition})
+}
+
+// lastCall 获取最后一次指定方法的调用
+func (m *memoryBizService) lastCall(t *testing.T, method string) memoryBizCall {
+	t.Helper()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := len(m.calls) - 1; i >= 0; i-- {
+		if m.calls[i].method == method {
+			return m.calls[i]
+		}
+	}
+	t.Fatalf("%s not called", method)
+	return memoryBizCall{}
+}
+
+func (m *memoryBizService) match(record *testRecord, condition map[string]any) bool {
+	columns := map[string]any{"id": record.ID, "user_id": record.UserID, "tenant_id": record.TenantID, "name": record.Name}
+	for key, expected := range condition {
+		actual, ok := columns[key]
+		if !ok {
+			continue
+		}
+		if values, ok := expected.([]any); ok {
+			found := false
+			for _, value := range values {
+				found = found || fmt.Sprint(value) == fmt.Sprint(actual)
+			}
+			if !found {
+				return false
+			}
+		} else if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *memoryBizService) find(condition map[string]any) []*testRecord {
+	var result []*testRecord
+	for _, record := range m.records {
+		if m.match(record, condition) {
+			copied := *record
+			result = append(result, &copied)
+		}
+	}
+	slices.SortFunc(result, func(a, b *testRecord) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+func (m *memoryBizService) SaveContext(ctx context.Context, save *testRecord) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("Save", ctx, nil, nil)
+	copied := *save
+	m.records[copied.ID] = &copied
+	return copied.ID, nil
+}
+
+func (m *memoryBizService) BaseQueryByIDContext(ctx context.Context, condition map[string]any, result *testRecord) (int64, error) {
+	return m.BaseQueryOneContext(ctx, condition, result)
+}
+
+func (m *memoryBizService) BaseQueryOneContext(ctx context.Context, condition map[string]any, result *testRecord) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("QueryOne", ctx, nil, condition)
+	records := m.find(condition)
+	if len(records) == 0 {
+		return 0, nil
+	}
+	*result = *records[0]
+	return 1, nil
+}
+
+func (m *memoryBizService) BaseQueryContext(ctx context.Context, condition map[string]any, result *[]*testRecord) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("Query", ctx, nil, condition)
+	*result = m.find(condition)
+	return int64(len(*result)), nil
+}
+
+func (m *memoryBizService) BaseQueryByPagerContext(ctx context.Context, condition map[string]any, pager *Pager[testRecord]) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("QueryByPager", ctx, nil, condition)
+	pager.Records = m.find(condition)
+	pager.Total = int64(len(pager.Records))
+	return nil
+}
+
+func (m *memoryBizService) BaseModifyByIDContext(ctx context.Context, update, condition map[string]any) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("ModifyByID", ctx, update, condition)
+	records := m.find(condition)
+	for _, record := range records {
+		if name, ok := update["name"]; ok {
+			m.records[record.ID].Name = fmt.Sprint(name)
+		}
+	}
+	return int64(len(records)), nil
+}
+
+func (m *memoryBizService) BaseRemoveByIDContext(ctx context.Context, condition map[string]any) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.record("RemoveByID", ctx, nil, condition)
+	records := m.find(condition)
+	for _, record := range records {
+		delete(m.records, record.ID)
+	}
+	return int64(len(records)), nil
+}
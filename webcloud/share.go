@@ -0,0 +1,341 @@
+package webcloud
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+// ConditionOr 查询条件中表示"或"的key 值为 []map[string]any 其中任一组条件满足即可 与其他条件为"且"的关系
+// 仅传递给实现了 ConditionOrSupported 的 BaseBizService
+const ConditionOr = "$or"
+
+// ConditionOrSupported 支持 ConditionOr 条件的业务服务 启用数据共享时 BaseBizService 必须实现
+type ConditionOrSupported interface {
+	// SupportConditionOr 声明查询、修改、删除的条件支持 ConditionOr
+	SupportConditionOr()
+}
+
+const (
+	AuditActionShare   = "share"   // 共享数据
+	AuditActionUnshare = "unshare" // 取消共享
+)
+
+// ShareAccess 共享的访问权限
+type ShareAccess string
+
+const (
+	ShareRead  ShareAccess = "read"  // 可查询
+	ShareWrite ShareAccess = "write" // 可查询与修改
+)
+
+// GranteeKind 被共享对象类型
+type GranteeKind string
+
+const (
+	GranteeIdentity GranteeKind = "identity" // 身份
+	GranteeGroup    GranteeKind = "group"    // 分组 认证信息需实现 GroupAuthority
+)
+
+// Grantee 被共享对象
+type Grantee struct {
+	Kind GranteeKind `json:"granteeKind"`
+	ID   string      `json:"granteeId"`
+}
+
+// valid 被共享对象的类型与标识是否有效
+func (g Grantee) valid() bool {
+	return g.ID != "" && (g.Kind == GranteeIdentity || g.Kind == GranteeGroup)
+}
+
+// Share 数据共享记录
+type Share struct {
+	Grantee
+	ResourceID string      `json:"resourceId"`
+	Access     ShareAccess `json:"access"`
+	GrantedBy  any         `json:"grantedBy"`
+	Time       time.Time   `json:"time"`
+}
+
+// GroupAuthority 含有分组的认证信息 Authority 的可选扩展 用于匹配共享给分组的数据
+type GroupAuthority interface {
+	GetGroups() []string
+}
+
+// ShareStore 数据共享存储
+type ShareStore interface {
+	// Grant 共享数据 同一被共享对象重复共享时覆盖访问权限
+	Grant(resource string, share *Share) error
+	// Revoke 取消共享 未共享时返回false
+	Revoke(resource, resourceID string, grantee Grantee) (bool, error)
+	// List 获取数据的全部共享
+	List(resource, resourceID string) ([]*Share, error)
+	// Accessible 获取被共享对象可访问的数据标识 write为true时仅返回可修改的数据
+	Accessible(resource string, grantees []Grantee, write bool) ([]string, error)
+}
+
+// MemoryShareStore 基于内存的数据共享存储
+type MemoryShareStore struct {
+	mutex  sync.RWMutex
+	shares map[string][]*Share
+}
+
+// NewMemoryShareStore 创建基于内存的数据共享存储
+func NewMemoryShareStore() *MemoryShareStore {
+	return &MemoryShareStore{shares: make(map[string][]*Share)}
+}
+
+func (m *MemoryShareStore) Grant(resource string, share *Share) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	shares := m.shares[resource]
+	for i, exist := range shares {
+		if exist.ResourceID == share.ResourceID && exist.Grantee == share.Grantee {
+			shares[i] = share
+			return nil
+		}
+	}
+	m.shares[resource] = append(shares, share)
+	return nil
+}
+
+func (m *MemoryShareStore) Revoke(resource, resourceID string, grantee Grantee) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	shares := m.shares[resource]
+	for i, exist := range shares {
+		if exist.ResourceID == resourceID && exist.Grantee == grantee {
+			m.shares[resource] = append(shares[:i:i], shares[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryShareStore) List(resource, resourceID string) ([]*Share, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return coll.SliceFilter(m.shares[resource], func(share *Share) bool {
+		return share.ResourceID == resourceID
+	}), nil
+}
+
+func (m *MemoryShareStore) Accessible(resource string, grantees []Grantee, write bool) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	var ids []string
+	for _, share := range m.shares[resource] {
+		if write && share.Access != ShareWrite {
+			continue
+		}
+		if coll.SliceContains(grantees, share.Grantee) && !coll.SliceContains(ids, share.ResourceID) {
+			ids = append(ids, share.ResourceID)
+		}
+	}
+	return ids, nil
+}
+
+// EnableSharing 启用数据共享 数据所有者可将数据共享给其他身份或分组
+// 查询类操作将同时返回本人的数据与共享给本人的数据 拥有写权限的共享可修改数据 删除与共享管理仅限所有者
+// 并注册 share/:id unshare/:id shares/:id 路由 BaseBizService 未实现 ConditionOrSupported 时触发panic
+func (b *BaseRouter[ID, S, M, Q, D]) EnableSharing(store ShareStore) *BaseRouter[ID, S, M, Q, D] {
+	if _, ok := b.baseBizService.(ConditionOrSupported); !ok {
+		panic(fmt.Errorf("sharing requires %T to implement ConditionOrSupported", b.baseBizService))
+	}
+	b.shareStore = store
+	return b
+}
+
+// shareWriteRequired 基础操作可通过共享访问时 返回是否需要写权限
+func shareWriteRequired(operation Operation) (write bool, shareable bool) {
+	switch operation {
+	case OperationQueryByID, OperationQueryOne, OperationQuery, OperationQueryByPage, OperationRevisions:
+		return false, true
+	case OperationModifyByID, OperationRollback, OperationTransition:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// authorityGrantees 获取认证信息对应的全部被共享对象
+func authorityGrantees[ID IDType](authority Authority[ID]) []Grantee {
+	grantees := []Grantee{{Kind: GranteeIdentity, ID: fmt.Sprint(authority.GetIdentityID())}}
+	if groupAuthority, ok := AuthorityAs[GroupAuthority](authority); ok {
+		for _, group := range groupAuthority.GetGroups() {
+			grantees = append(grantees, Grantee{Kind: GranteeGroup, ID: group})
+		}
+	}
+	return grantees
+}
+
+// applySharing 将共享给当前认证信息的数据合并至数据权限控制条件
+// 按主键操作时 已共享的数据不再限制所有者 其他查询以 ConditionOr 组合所有者条件与共享的数据
+func (b *BaseRouter[ID, S, M, Q, D]) applySharing(request *ginstarter.Request, authority Authority[ID], values, param map[string]any) {
+	if b.shareStore == nil {
+		return
+	}
+	operation, _ := GetOperation(request)
+	write, shareable := shareWriteRequired(operation)
+	if !shareable {
+		return
+	}
+	ownership := coll.MapFilterCollect(values, func(column string, value any) (string, any, bool) {
+		return column, value, column != b.tenantColumn
+	})
+	if len(ownership) == 0 {
+		return
+	}
+	ids, err := b.shareStore.Accessible(b.resource, authorityGrantees(authority), write)
+	if err != nil {
+		logger.Logrus().Errorln("load shares error:", b.resource, err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if id, ok := param["id"]; ok && !isSliceValue(id) {
+		if coll.SliceContains(ids, fmt.Sprint(id)) {
+			for column := range ownership {
+				delete(values, column)
+			}
+		}
+		return
+	}
+	granted := make([]any, 0, len(ids))
+	for _, id := range ids {
+		if v, err := CovertStringToID[ID](id); err == nil {
+			granted = append(granted, v)
+		}
+	}
+	for column := range ownership {
+		delete(values, column)
+	}
+	values[ConditionOr] = []map[string]any{ownership, {"id": granted}}
+}
+
+// isSliceValue 条件值是否为多值条件
+func isSliceValue(value any) bool {
+	return value != nil && reflect.TypeOf(value).Kind() == reflect.Slice
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) registerShareHandler(router *ginstarter.RouterWrapper) {
+	// 共享数据
+	router.POST1("share/:id", []string{gin.MIMEJSON}, b.Share())
+	// 取消共享
+	router.POST1("unshare/:id", []string{gin.MIMEJSON}, b.Unshare())
+	// 查询数据的全部共享
+	router.GET("shares/:id", b.ListShares())
+}
+
+// ownedRecord 检查当前请求是否为数据所有者
+func (b *BaseRouter[ID, S, M, Q, D]) ownedRecord(request *ginstarter.Request, operation Operation) (ID, ginstarter.Response, error) {
+	id, err := CovertStringToID[ID](request.GetPathParam("id"))
+	if err != nil {
+		return id, nil, err
+	}
+	param := map[string]any{"id": id}
+	if response := b.limitCondition(request, param); response != nil {
+		return id, response, nil
+	}
+	if !b.applyPolicies(request, operation, param, param) {
+		return id, ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+	}
+	if b.snapshot(request, param) == nil {
+		return id, ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+	}
+	return id, nil, nil
+}
+
+// ShareRequest 共享数据请求
+type ShareRequest struct {
+	Grantee
+	Access ShareAccess `json:"access"`
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Share() ginstarter.HandlerWrapper {
+	return b.wrap(OperationShare, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		var param ShareRequest
+		if err := b.bindJson(request, &param); err != nil {
+			return bodyErrorResponse(err), nil
+		}
+		if param.Access == "" {
+			param.Access = ShareRead
+		}
+		if !param.valid() || (param.Access != ShareRead && param.Access != ShareWrite) {
+			return ginstarter.RespRestBadParameters(), nil
+		}
+		id, response, err := b.ownedRecord(request, OperationShare)
+		if err != nil || response != nil {
+			return response, err
+		}
+		share := &Share{
+			Grantee:    param.Grantee,
+			ResourceID: fmt.Sprint(id),
+			Access:     param.Access,
+			Time:       time.Now(),
+		}
+		if authority := b.GetAuthorityData(request, true); authority != nil {
+			share.GrantedBy = authority.GetIdentityID()
+		}
+		err = b.shareStore.Grant(b.resource, share)
+		b.auditChange(request, AuditActionShare, id, nil, nil, map[string]any{
+			"granteeKind": param.Kind, "granteeId": param.ID, "access": param.Access,
+		}, 1, err)
+		if err != nil {
+			return nil, err
+		}
+		return ginstarter.RespRestSuccess(), nil
+	})
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Unshare() ginstarter.HandlerWrapper {
+	return b.wrap(OperationUnshare, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		var param Grantee
+		if err := b.bindJson(request, &param); err != nil {
+			return bodyErrorResponse(err), nil
+		}
+		if !param.valid() {
+			return ginstarter.RespRestBadParameters(), nil
+		}
+		id, response, err := b.ownedRecord(request, OperationUnshare)
+		if err != nil || response != nil {
+			return response, err
+		}
+		revoked, err := b.shareStore.Revoke(b.resource, fmt.Sprint(id), param)
+		var row int64
+		if revoked {
+			row = 1
+		}
+		b.auditChange(request, AuditActionUnshare, id, nil, map[string]any{
+			"granteeKind": param.Kind, "granteeId": param.ID,
+		}, nil, row, err)
+		if err != nil {
+			return nil, err
+		}
+		if !revoked {
+			return ginstarter.RespRestBadParameters(), nil
+		}
+		return ginstarter.RespRestSuccess(), nil
+	})
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) ListShares() ginstarter.HandlerWrapper {
+	return b.wrap(OperationListShares, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		id, response, err := b.ownedRecord(request, OperationListShares)
+		if err != nil || response != nil {
+			return response, err
+		}
+		shares, err := b.shareStore.List(b.resource, fmt.Sprint(id))
+		if err != nil {
+			return nil, err
+		}
+		return ginstarter.RespRestSuccess(shares), nil
+	})
+}
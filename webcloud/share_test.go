@@ -0,0 +1,65 @@
+package webcloud
+
+import (
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+type testBizService struct {
+	BaseBizService[int64, testRecord, testRecord, testRecord, testRecord]
+}
+
+type testOrBizService struct {
+	testBizService
+}
+
+func (testOrBizService) SupportConditionOr() {}
+
+func TestEnableSharingRequiresConditionOr(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("sharing without ConditionOr support should panic")
+			}
+		}()
+		(&testRouter{baseBizService: testBizService{}}).EnableSharing(NewMemoryShareStore())
+	}()
+	router := (&testRouter{baseBizService: testOrBizService{}}).EnableSharing(NewMemoryShareStore())
+	if router.shareStore == nil {
+		t.Fatal("sharing not enabled")
+	}
+}
+
+type testOrMemoryBizService struct {
+	*memoryBizService
+}
+
+func (testOrMemoryBizService) SupportConditionOr() {}
+
+func TestShareGranteeKind(t *testing.T) {
+	store := NewMemoryShareStore()
+	fetch := func(*ginstarter.Request) Authority[int64] { return &testAuthority{id: 1} }
+	router := NewBaseRouterWithAuthority[int64, testRecord, testRecord, testRecord, testRecord](
+		testOrMemoryBizService{newMemoryBizService(testRecord{ID: 1, UserID: 1})}, fetch, "UserID").
+		EnableSharing(store)
+	params := map[string]string{"id": "1"}
+	cases := []struct {
+		name    string
+		handler ginstarter.HandlerWrapper
+		body    string
+		status  ginstarter.StatusCode
+	}{
+		{"share unknown kind", router.Share(), `{"granteeKind":"role","granteeId":"2"}`, ginstarter.StatusCodeBadRequestParameters},
+		{"share identity", router.Share(), `{"granteeKind":"identity","granteeId":"2"}`, ginstarter.StatusCodeSuccess},
+		{"unshare unknown kind", router.Unshare(), `{"granteeKind":"role","granteeId":"2"}`, ginstarter.StatusCodeBadRequestParameters},
+		{"unshare missing id", router.Unshare(), `{"granteeKind":"identity"}`, ginstarter.StatusCodeBadRequestParameters},
+		{"unshare identity", router.Unshare(), `{"granteeKind":"identity","granteeId":"2"}`, ginstarter.StatusCodeSuccess},
+		{"unshare revoked", router.Unshare(), `{"granteeKind":"identity","granteeId":"2"}`, ginstarter.StatusCodeBadRequestParameters},
+	}
+	for _, c := range cases {
+		if result := serve(t, c.handler, testCall{params: params, body: c.body}); result.status != c.status {
+			t.Errorf("%s: status %d %s, want %d", c.name, result.status, result.message, c.status)
+		}
+	}
+}
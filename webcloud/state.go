@@ -0,0 +1,219 @@
+package webcloud
+
+import (
+	sdkjson "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/acexy/golang-toolkit/util/coll"
+	"github.com/acexy/golang-toolkit/util/str"
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+const AuditActionTransition = "transition" // 状态迁移
+
+// StatusCodeConflict 状态迁移时数据的状态已被并发修改
+const StatusCodeConflict ginstarter.StatusCode = http.StatusConflict
+
+// StateTransition 状态迁移
+type StateTransition struct {
+	Event       string   // 触发迁移的事件
+	From        []string // 允许迁移的源状态
+	To          string   // 目标状态
+	Permissions []string // 需拥有全部权限 认证信息需实现 PermissionAuthority
+	Roles       []string // 需拥有任一角色 认证信息需实现 PermissionAuthority
+}
+
+// TransitionContext 状态迁移上下文
+type TransitionContext[ID IDType] struct {
+	Request   *ginstarter.Request
+	Authority Authority[ID]
+	ID        ID
+	Event     string
+	From      string
+	To        string
+	Record    map[string]any // 迁移前的数据 key为json字段名
+}
+
+// TransitionHook 状态迁移钩子
+type TransitionHook[ID IDType] func(ctx *TransitionContext[ID]) error
+
+// StateMachine 状态字段的状态机
+type StateMachine[ID IDType] struct {
+	FieldName   string             // 状态字段的结构体字段名
+	States      []string           // 全部状态
+	Initial     string             // 保存数据时强制设置的初始状态 必须
+	Transitions []StateTransition  // 允许的迁移
+	Before      TransitionHook[ID] // 迁移前执行 返回错误时终止迁移
+	After       TransitionHook[ID] // 迁移成功后执行 错误仅记录日志
+}
+
+type stateMachine[ID IDType] struct {
+	StateMachine[ID]
+	column  string
+	initial any // 与保存结构体字段类型一致的初始状态
+}
+
+// SetStateMachine 设置状态字段的状态机 状态字段不再允许通过保存、修改、回滚自由设置 保存时请求携带状态字段响应参数错误
+// 保存的数据始终设置为初始状态 之后仅能通过 transition/:id/:event 路由按声明的迁移变更 状态机配置有误时触发panic
+func (b *BaseRouter[ID, S, M, Q, D]) SetStateMachine(machine StateMachine[ID]) *BaseRouter[ID, S, M, Q, D] {
+	if machine.FieldName == "" || len(machine.States) == 0 || machine.Initial == "" {
+		panic(errors.New("state machine requires field name, states and initial state"))
+	}
+	if !coll.SliceContains(machine.States, machine.Initial) {
+		panic(fmt.Errorf("unknown initial state: %s", machine.Initial))
+	}
+	for _, transition := range machine.Transitions {
+		if transition.Event == "" || !coll.SliceContains(machine.States, transition.To) ||
+			!coll.SliceIsSubset(transition.From, machine.States) {
+			panic(fmt.Errorf("invalid state transition: %s", transition.Event))
+		}
+	}
+	b.stateMachine = &stateMachine[ID]{
+		StateMachine: machine,
+		column:       structName2Column(machine.FieldName),
+		initial:      machine.Initial,
+	}
+	if field, ok := reflect.TypeFor[S]().FieldByName(machine.FieldName); ok {
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			b.stateMachine.initial = restoreSnapshotValue(sdkjson.Number(machine.Initial))
+		}
+	}
+	return b
+}
+
+// stateColumn 状态字段的数据库字段名 未设置状态机时为空
+func (b *BaseRouter[ID, S, M, Q, D]) stateColumn() string {
+	if b.stateMachine == nil {
+		return ""
+	}
+	return b.stateMachine.column
+}
+
+// initialStateValues 保存数据时需强制设置的初始状态
+func (b *BaseRouter[ID, S, M, Q, D]) initialStateValues() map[string]any {
+	if b.stateMachine == nil {
+		return nil
+	}
+	return map[string]any{b.stateMachine.column: b.stateMachine.initial}
+}
+
+// findTransition 查找可从当前状态由事件触发的迁移
+func (s *stateMachine[ID]) findTransition(event, from string) (StateTransition, bool, bool) {
+	var known bool
+	for _, transition := range s.Transitions {
+		if transition.Event != event {
+			continue
+		}
+		known = true
+		if coll.SliceContains(transition.From, from) {
+			return transition, true, true
+		}
+	}
+	return StateTransition{}, known, false
+}
+
+// stateValue 将目标状态转换为与当前状态值一致的类型
+func stateValue(current any, state string) any {
+	if _, ok := current.(sdkjson.Number); ok {
+		return restoreSnapshotValue(sdkjson.Number(state))
+	}
+	return state
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) registerTransitionHandler(router *ginstarter.RouterWrapper) {
+	// 触发状态迁移
+	router.POST("transition/:id/:event", b.Transition())
+}
+
+func (b *BaseRouter[ID, S, M, Q, D]) Transition() ginstarter.HandlerWrapper {
+	return b.wrap(OperationTransition, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		machine := b.stateMachine
+		id, err := CovertStringToID[ID](request.GetPathParam("id"))
+		if err != nil {
+			return nil, err
+		}
+		event := request.GetPathParam("event")
+		param := map[string]any{"id": id}
+		if response := b.limitCondition(request, param); response != nil {
+			return response, nil
+		}
+		before := b.snapshot(request, param)
+		if before == nil {
+			return ginstarter.RespRestBadParameters("record not found"), nil
+		}
+		var current any
+		for k, v := range before {
+			if structName2Column(k) == machine.column {
+				current = v
+				break
+			}
+		}
+		from := fmt.Sprint(current)
+		transition, known, ok := machine.findTransition(event, from)
+		if !known {
+			return ginstarter.RespRestBadParameters("unknown event"), nil
+		}
+		if !ok {
+			return ginstarter.RespRestBadParameters("transition not allowed from state " + from), nil
+		}
+		var authority Authority[ID]
+		if b.authorityFetch != nil {
+			authority = b.GetAuthorityData(request, true)
+		}
+		if len(transition.Permissions) > 0 || len(transition.Roles) > 0 {
+			if authority == nil {
+				return ginstarter.RespRestUnAuthorized(), nil
+			}
+			if !HasPermission(authority, transition.Permissions...) || !HasRole(authority, transition.Roles...) {
+				logger.Logrus().Warningln("transition", event, "forbidden, identity:", authority.GetIdentityID())
+				return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+			}
+		}
+		to := stateValue(current, transition.To)
+		if !b.applyPolicies(request, OperationTransition, map[string]any{machine.column: to}, param) {
+			return ginstarter.RespRestStatusError(ginstarter.StatusCodeForbidden), nil
+		}
+		ctx := &TransitionContext[ID]{
+			Request:   request,
+			Authority: authority,
+			ID:        id,
+			Event:     event,
+			From:      from,
+			To:        transition.To,
+			Record:    before,
+		}
+		if machine.Before != nil {
+			if err = machine.Before(ctx); err != nil {
+				return ginstarter.RespRestBadParameters(err.Error()), nil
+			}
+		}
+		// 以当前状态作为条件 避免并发迁移
+		param[machine.column] = restoreSnapshotValue(current)
+		row, err := b.bizService.BaseModifyByIDContext(b.requestContext(request), map[string]any{str.SnakeToCamel(machine.column): to}, param)
+		delete(param, machine.column)
+		var after map[string]any
+		if row > 0 {
+			after = b.snapshot(request, param)
+		}
+		b.auditChange(request, AuditActionTransition, id, []string{machine.column}, before, after, row, err)
+		if err != nil {
+			return nil, err
+		}
+		if row == 0 {
+			return ginstarter.RespRestStatusError(StatusCodeConflict, "state changed"), nil
+		}
+		b.recordRevision(request, AuditActionTransition, id, after)
+		if machine.After != nil {
+			if err = machine.After(ctx); err != nil {
+				logger.Logrus().Errorln("after transition hook error:", event, id, err)
+			}
+		}
+		return ginstarter.RespRestSuccess(map[string]string{"from": from, "to": transition.To}), nil
+	})
+}
@@ -0,0 +1,96 @@
+package webcloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+type testStateRecord struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type testStateRouter = BaseRouter[int64, testStateRecord, testStateRecord, testStateRecord, testStateRecord]
+
+func TestStateMachineRequiresInitial(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("state machine without initial state should panic")
+		}
+	}()
+	(&testStateRouter{}).SetStateMachine(StateMachine[int64]{FieldName: "Status", States: []string{"draft", "done"}})
+}
+
+func TestSaveRejectsStateField(t *testing.T) {
+	router := (&testStateRouter{}).SetStateMachine(StateMachine[int64]{
+		FieldName: "Status",
+		States:    []string{"draft", "done"},
+		Initial:   "draft",
+	})
+	allowed := []string{"name", "status"}
+	if router.checkColumns(allowed, map[string]any{"name": "a", "status": "done"}, ModeSave) {
+		t.Fatal("save with state field should be rejected")
+	}
+	param := testStateRecord{Name: "a"}
+	if err := setStructColumns(&param, router.initialStateValues()); err != nil {
+		t.Fatal(err)
+	}
+	if param.Status != "draft" {
+		t.Fatalf("initial state not set: %q", param.Status)
+	}
+}
+
+// testStateBizService 状态数据的业务服务 modified 为修改影响的行数
+type testStateBizService struct {
+	BaseBizService[int64, testStateRecord, testStateRecord, testStateRecord, testStateRecord]
+	BaseBizServiceContext[int64, testStateRecord, testStateRecord]
+	modified int64
+	update   map[string]any
+}
+
+func (s *testStateBizService) BaseQueryByIDContext(_ context.Context, _ map[string]any, result *testStateRecord) (int64, error) {
+	*result = testStateRecord{ID: 1, Status: "draft"}
+	return 1, nil
+}
+
+func (s *testStateBizService) BaseModifyByIDContext(_ context.Context, update, _ map[string]any) (int64, error) {
+	s.update = update
+	return s.modified, nil
+}
+
+func TestTransition(t *testing.T) {
+	cases := []struct {
+		name     string
+		event    string
+		modified int64
+		status   ginstarter.StatusCode
+	}{
+		{"success", "publish", 1, ginstarter.StatusCodeSuccess},
+		{"concurrent change", "publish", 0, StatusCodeConflict},
+		{"unknown event", "archive", 1, ginstarter.StatusCodeBadRequestParameters},
+		{"not allowed from state", "reopen", 1, ginstarter.StatusCodeBadRequestParameters},
+	}
+	for _, c := range cases {
+		service := &testStateBizService{modified: c.modified}
+		router := NewBaseRouter[int64, testStateRecord, testStateRecord, testStateRecord, testStateRecord](service).
+			SetStateMachine(StateMachine[int64]{
+				FieldName: "Status",
+				States:    []string{"draft", "done"},
+				Initial:   "draft",
+				Transitions: []StateTransition{
+					{Event: "publish", From: []string{"draft"}, To: "done"},
+					{Event: "reopen", From: []string{"done"}, To: "draft"},
+				},
+			})
+		result := serve(t, router.Transition(), testCall{params: map[string]string{"id": "1", "event": c.event}})
+		if result.status != c.status {
+			t.Errorf("%s: status %d %s, want %d", c.name, result.status, result.message, c.status)
+		}
+		if c.status == ginstarter.StatusCodeSuccess && (len(service.update) != 1 || service.update["status"] != "done") {
+			t.Errorf("%s: update should be keyed by json field name: %v", c.name, service.update)
+		}
+	}
+}
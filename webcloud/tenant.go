@@ -0,0 +1,54 @@
+package webcloud
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/acexy/golang-toolkit/util/str"
+)
+
+// TenantAuthority 含有租户信息的认证信息 Authority 的可选扩展
+type TenantAuthority interface {
+	// GetTenantID 获取所属租户标识
+	GetTenantID() any
+}
+
+// GetTenantID 获取认证信息所属的租户 认证信息未实现 TenantAuthority 时返回false
+func GetTenantID[ID IDType](authority Authority[ID]) (any, bool) {
+	tenantAuthority, ok := AuthorityAs[TenantAuthority](authority)
+	if !ok {
+		return nil, false
+	}
+	tenantID := tenantAuthority.GetTenantID()
+	return tenantID, tenantID != nil
+}
+
+// SetTenantLimit 启用多租户隔离 租户字段将以认证信息所属租户强制设置于查询、修改、删除的条件及保存的数据中
+// 可与数据权限控制字段同时使用 请求方不允许在任何操作中传入租户字段
+// 路由需通过 NewBaseRouterWithAuthority 创建 认证信息需实现 TenantAuthority
+// 保存结构体S与查询结构体Q需含有租户字段 否则触发panic
+func (b *BaseRouter[ID, S, M, Q, D]) SetTenantLimit(tenantFieldName string) *BaseRouter[ID, S, M, Q, D] {
+	if b.authorityFetch == nil {
+		panic(errors.New("tenant limit requires authority fetch"))
+	}
+	for _, t := range []reflect.Type{reflect.TypeFor[S](), reflect.TypeFor[Q]()} {
+		if !hasStructField(t, tenantFieldName) {
+			panic(fmt.Errorf("tenant field %s not found in %s", tenantFieldName, t))
+		}
+	}
+	b.tenantColumn = str.CamelToSnake(str.LowFirstChar(tenantFieldName))
+	return b
+}
+
+// hasStructField 结构体或其指针是否含有指定字段 包含匿名嵌入结构体的字段
+func hasStructField(t reflect.Type, fieldName string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := t.FieldByName(fieldName)
+	return ok
+}
@@ -0,0 +1,45 @@
+package webcloud
+
+import (
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+)
+
+type testNoTenantRecord struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func testAuthorityFetch(*ginstarter.Request) Authority[int64] { return nil }
+
+func TestSetTenantLimit(t *testing.T) {
+	router := (&testRouter{authorityFetch: testAuthorityFetch}).SetTenantLimit("TenantID")
+	if router.tenantColumn != "tenant_id" {
+		t.Fatalf("unexpected tenant column: %s", router.tenantColumn)
+	}
+}
+
+func TestSetTenantLimitMissingField(t *testing.T) {
+	cases := map[string]func(){
+		"unknown field": func() {
+			(&testRouter{authorityFetch: testAuthorityFetch}).SetTenantLimit("OrgID")
+		},
+		"missing in save struct": func() {
+			(&BaseRouter[int64, testNoTenantRecord, testRecord, testRecord, testRecord]{authorityFetch: testAuthorityFetch}).SetTenantLimit("TenantID")
+		},
+		"missing in query struct": func() {
+			(&BaseRouter[int64, testRecord, testRecord, testNoTenantRecord, testRecord]{authorityFetch: testAuthorityFetch}).SetTenantLimit("TenantID")
+		},
+	}
+	for name, setup := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			setup()
+		}()
+	}
+}
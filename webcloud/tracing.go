@@ -0,0 +1,194 @@
+package webcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName 创建Tracer时使用的instrumentation名称 例: otel.Tracer(webcloud.TracerName)
+const TracerName = "github.com/golang-acexy/cloud-web/webcloud"
+
+// traceContext 以W3C Trace Context格式传播追踪标识
+var traceContext = propagation.TraceContext{}
+
+// NewStdoutTracerProvider 创建以JSON格式输出Span至writer的TracerProvider 用于本地调试
+// 使用完毕后需调用 Shutdown
+func NewStdoutTracerProvider(writer io.Writer) (*sdktrace.TracerProvider, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), nil
+}
+
+// ContextWithTraceParent 将W3C traceparent头作为上游Span写入上下文 格式有误时返回原上下文
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return traceContext.Extract(ctx, propagation.MapCarrier{HeaderTraceParent: traceParent})
+}
+
+// TraceParent 获取上下文中Span的W3C traceparent头 用于请求下游服务 不存在Span时返回空
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier.Get(HeaderTraceParent)
+}
+
+// finishSpan 结束Span err不为空时标记为错误
+func finishSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+// SetTracer 启用追踪 每个基础操作创建一个Span 业务服务的每次调用创建子Span
+// 请求的traceparent头作为上游Span 响应通过traceresponse头返回当前Span
+// tracer 通常为 otel.Tracer(TracerName) 或 TracerProvider.Tracer(TracerName)
+func (b *BaseRouter[ID, S, M, Q, D]) SetTracer(tracer trace.Tracer) *BaseRouter[ID, S, M, Q, D] {
+	b.tracer = tracer
+	if traced, ok := b.bizService.(*tracedBizService[ID, S, D]); ok {
+		traced.tracer = tracer
+	} else {
+		b.bizService = &tracedBizService[ID, S, D]{next: b.bizService, tracer: tracer, resource: b.resource}
+	}
+	return b
+}
+
+// trace 在Span中执行基础操作
+func (b *BaseRouter[ID, S, M, Q, D]) trace(request *ginstarter.Request, operation Operation, handler ginstarter.HandlerWrapper) (response ginstarter.Response, err error) {
+	if b.tracer == nil {
+		return handler(request)
+	}
+	rawRequest := request.RawGinContext().Request
+	ctx := traceContext.Extract(rawRequest.Context(), propagation.HeaderCarrier(rawRequest.Header))
+	ctx, span := b.tracer.Start(ctx, b.resource+"."+string(operation), trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("resource", b.resource),
+			attribute.String("operation", string(operation)),
+			attribute.String("http.path", request.RequestPath()),
+		))
+	request.RawGinContext().Request = rawRequest.WithContext(ctx)
+	completed := false
+	defer func() {
+		// 仅读取已获取的认证信息 认证失败时不再重复执行AuthorityFetch
+		if authority := cachedAuthority[ID](request); authority != nil {
+			span.SetAttributes(
+				attribute.String("authority.id", fmt.Sprint(authority.GetIdentityID())),
+				attribute.String("authority.platform", string(authority.GetPlatform())),
+			)
+		}
+		if rows, ok := request.GetValue(ctxKeyRows); ok {
+			span.SetAttributes(attribute.String("rows", fmt.Sprint(rows)))
+		}
+		if !completed {
+			finishSpan(span, errPanic)
+			return
+		}
+		result, statusCode := responseResult(response, err)
+		span.SetAttributes(attribute.String("result", result), attribute.Int("status_code", statusCode))
+		if err == nil && result == ResultServerError {
+			finishSpan(span, errServerResult)
+			return
+		}
+		finishSpan(span, err)
+	}()
+	response, err = handler(request)
+	completed = true
+	if response != nil {
+		response.Data().AddHeader(HeaderTraceResponse, TraceParent(ctx))
+	}
+	return
+}
+
+var (
+	errPanic        = errors.New("handler panic")
+	errServerResult = errors.New("server error response")
+)
+
+// tracedBizService 为业务服务的每次调用创建子Span
+type tracedBizService[ID IDType, S, D any] struct {
+	next     BaseBizServiceContext[ID, S, D]
+	tracer   trace.Tracer
+	resource string
+}
+
+func (t *tracedBizService[ID, S, D]) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	resource := t.resource
+	metadata, ok := MetadataFromContext(ctx)
+	if ok {
+		resource = metadata.Resource
+	}
+	ctx, span := t.tracer.Start(ctx, resource+"."+method, trace.WithAttributes(attribute.String("resource", resource)))
+	if ok {
+		span.SetAttributes(attribute.String("operation", string(metadata.Operation)))
+	}
+	return ctx, span
+}
+
+func (t *tracedBizService[ID, S, D]) finish(span trace.Span, row int64, err error) {
+	span.SetAttributes(attribute.Int64("rows", row))
+	finishSpan(span, err)
+}
+
+func (t *tracedBizService[ID, S, D]) SaveContext(ctx context.Context, save *S) (ID, error) {
+	ctx, span := t.start(ctx, "Save")
+	id, err := t.next.SaveContext(ctx, save)
+	finishSpan(span, err)
+	return id, err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseQueryByIDContext(ctx context.Context, condition map[string]any, result *D) (int64, error) {
+	ctx, span := t.start(ctx, "BaseQueryByID")
+	row, err := t.next.BaseQueryByIDContext(ctx, condition, result)
+	t.finish(span, row, err)
+	return row, err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseQueryOneContext(ctx context.Context, condition map[string]any, result *D) (int64, error) {
+	ctx, span := t.start(ctx, "BaseQueryOne")
+	row, err := t.next.BaseQueryOneContext(ctx, condition, result)
+	t.finish(span, row, err)
+	return row, err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseQueryContext(ctx context.Context, condition map[string]any, result *[]*D) (int64, error) {
+	ctx, span := t.start(ctx, "BaseQuery")
+	row, err := t.next.BaseQueryContext(ctx, condition, result)
+	t.finish(span, row, err)
+	return row, err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseQueryByPagerContext(ctx context.Context, condition map[string]any, pager *Pager[D]) error {
+	ctx, span := t.start(ctx, "BaseQueryByPager")
+	err := t.next.BaseQueryByPagerContext(ctx, condition, pager)
+	span.SetAttributes(attribute.Int64("total", pager.Total))
+	t.finish(span, int64(len(pager.Records)), err)
+	return err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseModifyByIDContext(ctx context.Context, update, condition map[string]any) (int64, error) {
+	ctx, span := t.start(ctx, "BaseModifyByID")
+	row, err := t.next.BaseModifyByIDContext(ctx, update, condition)
+	t.finish(span, row, err)
+	return row, err
+}
+
+func (t *tracedBizService[ID, S, D]) BaseRemoveByIDContext(ctx context.Context, condition map[string]any) (int64, error) {
+	ctx, span := t.start(ctx, "BaseRemoveByID")
+	row, err := t.next.BaseRemoveByIDContext(ctx, condition)
+	t.finish(span, row, err)
+	return row, err
+}
@@ -0,0 +1,147 @@
+package webcloud
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/golang-acexy/starter-gin/ginstarter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func tracedRouter(service *memoryBizService) (*testRouter, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	router := NewBaseRouter[int64, testRecord, testRecord, testRecord, testRecord](service).SetTracer(provider.Tracer(TracerName))
+	return router, exporter
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTraceParentChildSpans(t *testing.T) {
+	service := newMemoryBizService(testRecord{ID: 1, Name: "a"})
+	router, exporter := tracedRouter(service)
+	result := serve(t, router.QueryById(), testCall{
+		method: http.MethodGet,
+		params: map[string]string{"id": "1"},
+		header: map[string]string{HeaderTraceParent: testTraceParent},
+	})
+	if result.status != ginstarter.StatusCodeSuccess {
+		t.Fatalf("query failed: %d %s", result.status, result.message)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected operation and service spans, got %d", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name != "testRecord.query-by-id" || server.SpanKind != trace.SpanKindServer || child.Name != "testRecord.BaseQueryByID" {
+		t.Fatalf("unexpected spans: %s %s", server.Name, child.Name)
+	}
+	if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		server.Parent.SpanID().String() != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Fatalf("operation span not continued from traceparent: %v parent %v", server.SpanContext, server.Parent)
+	}
+	if child.Parent.SpanID() != server.SpanContext.SpanID() || child.SpanContext.TraceID() != server.SpanContext.TraceID() {
+		t.Fatalf("service span is not a child of the operation span")
+	}
+	if rows, ok := spanAttribute(child, "rows"); !ok || rows.AsInt64() != 1 {
+		t.Fatalf("unexpected rows attribute: %v", rows)
+	}
+	if server.Status.Code != codes.Ok || child.Status.Code != codes.Ok {
+		t.Fatalf("unexpected span status: %v %v", server.Status, child.Status)
+	}
+
+	// traceresponse 与传递至业务服务的traceparent均为当前操作的Span
+	expected := "00-" + server.SpanContext.TraceID().String() + "-" + server.SpanContext.SpanID().String() + "-01"
+	if traceResponse := result.header.Get(HeaderTraceResponse); traceResponse != expected {
+		t.Fatalf("traceresponse %q, want %q", traceResponse, expected)
+	}
+	metadata, ok := MetadataFromContext(service.lastCall(t, "QueryOne").ctx)
+	if !ok || metadata.TraceParent != expected {
+		t.Fatalf("metadata traceparent %+v, want %q", metadata, expected)
+	}
+}
+
+func TestTraceWithoutParent(t *testing.T) {
+	router, exporter := tracedRouter(newMemoryBizService())
+	serve(t, router.QueryById(), testCall{
+		method: http.MethodGet,
+		params: map[string]string{"id": "1"},
+		header: map[string]string{HeaderTraceParent: "00-invalid"},
+	})
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected operation and service spans, got %d", len(spans))
+	}
+	server := spans[1]
+	if server.Parent.IsValid() || !server.SpanContext.IsValid() {
+		t.Fatalf("invalid traceparent should start a new trace: parent %v", server.Parent)
+	}
+}
+
+func TestTraceErrorStatus(t *testing.T) {
+	router, exporter := tracedRouter(newMemoryBizService())
+	handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		panic("boom")
+	})
+	serve(t, handler, testCall{method: http.MethodGet})
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Error || spans[0].Status.Description != errPanic.Error() {
+		t.Fatalf("panic not recorded as error: %+v", spans)
+	}
+}
+
+func TestContextWithTraceParent(t *testing.T) {
+	ctx := ContextWithTraceParent(context.Background(), testTraceParent)
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsRemote() || spanContext.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("traceparent not extracted: %v", spanContext)
+	}
+	if traceParent := TraceParent(ctx); traceParent != testTraceParent {
+		t.Fatalf("traceparent round trip %q, want %q", traceParent, testTraceParent)
+	}
+	if ctx = ContextWithTraceParent(context.Background(), "ff-"+testTraceParent[3:]); TraceParent(ctx) != "" {
+		t.Fatal("invalid version should be ignored")
+	}
+}
+
+func TestTraceAuthorityAttributes(t *testing.T) {
+	router, exporter := tracedRouter(newMemoryBizService())
+	calls := 0
+	router.authorityFetch = func(request *ginstarter.Request) Authority[int64] {
+		calls++
+		return &testAuthority{id: 9}
+	}
+	handler := router.wrap(OperationQueryByID, func(request *ginstarter.Request) (ginstarter.Response, error) {
+		router.GetAuthorityData(request)
+		return ginstarter.RespRestSuccess(), nil
+	})
+	serve(t, handler, testCall{method: http.MethodGet})
+	if id, ok := spanAttribute(exporter.GetSpans()[0], "authority.id"); !ok || id.AsString() != "9" {
+		t.Fatalf("unexpected authority attribute: %v", id)
+	}
+
+	exporter.Reset()
+	router.authorityFetch = func(request *ginstarter.Request) Authority[int64] {
+		calls++
+		AbortRequest(request, http.StatusUnauthorized, ErrUnAuthority)
+		return nil
+	}
+	serve(t, handler, testCall{method: http.MethodGet})
+	if _, ok := spanAttribute(exporter.GetSpans()[0], "authority.id"); ok || calls != 2 {
+		t.Fatalf("failed authority fetch executed %d times", calls)
+	}
+}
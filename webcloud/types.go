@@ -9,6 +9,56 @@ import (
 
 type Platform string
 
+// Operation 基础路由的操作类型
+type Operation string
+
+const (
+	OperationSave        Operation = "save"
+	OperationQueryByID   Operation = "query-by-id"
+	OperationQueryOne    Operation = "query-one"
+	OperationQuery       Operation = "query"
+	OperationQueryByPage Operation = "query-by-page"
+	OperationModifyByID  Operation = "modify-by-id"
+	OperationRemoveByID  Operation = "remove-by-id"
+	OperationRevisions   Operation = "revisions"   // 查询修订版本 启用修订版本时有效
+	OperationRollback    Operation = "rollback"    // 回滚至修订版本 启用修订版本时有效
+	OperationShare       Operation = "share"       // 共享数据 启用数据共享时有效
+	OperationUnshare     Operation = "unshare"     // 取消共享 启用数据共享时有效
+	OperationListShares  Operation = "list-shares" // 查询共享 启用数据共享时有效
+	OperationTransition  Operation = "transition"  // 状态迁移 设置状态机时有效
+)
+
+// Mode 获取基础操作的读写模式
+func (o Operation) Mode() Mode {
+	switch o {
+	case OperationSave:
+		return ModeSave
+	case OperationModifyByID, OperationRollback, OperationShare, OperationUnshare, OperationTransition:
+		return ModeModify
+	case OperationRemoveByID:
+		return ModeRemove
+	default:
+		return ModeQuery
+	}
+}
+
+// AllOperations 全部基础操作
+var AllOperations = []Operation{
+	OperationSave,
+	OperationQueryByID,
+	OperationQueryOne,
+	OperationQuery,
+	OperationQueryByPage,
+	OperationModifyByID,
+	OperationRemoveByID,
+	OperationRevisions,
+	OperationRollback,
+	OperationShare,
+	OperationUnshare,
+	OperationListShares,
+	OperationTransition,
+}
+
 // IDType 主键类型
 type IDType interface {
 	~int | ~uint | ~int32 | ~uint32 | ~int64 | ~uint64 | ~string
@@ -89,7 +139,7 @@ type BaseBizService[ID IDType, S, M, Q, D any] interface {
 	// BaseQueryByPager 分页查询
 	BaseQueryByPager(condition map[string]any, pager *Pager[D]) error
 
-	// BaseModifyByID 通过主键修改数据
+	// BaseModifyByID 通过主键修改数据 update 的key为json字段名 condition 的key为数据库字段名
 	BaseModifyByID(update, condition map[string]any) (int64, error)
 
 	// BaseRemoveByID 通过主键删除数据